@@ -6,11 +6,14 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"math/rand"
+	"net"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/OWASP/Amass/v3/config"
@@ -19,6 +22,7 @@ import (
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/resolvers"
 	"github.com/OWASP/Amass/v3/services"
+	"github.com/OWASP/Amass/v3/services/sources"
 	"github.com/OWASP/Amass/v3/stringset"
 	"github.com/fatih/color"
 	"github.com/miekg/dns"
@@ -28,26 +32,120 @@ const (
 	dnsUsageMsg = "dns [options]"
 )
 
+// dnsOptionArgs holds the boolean/value flags that shape how the dns
+// subcommand resolves and reports names, including the EDNS(0) behavior
+// requested via -dnssec/-subnet/-bufsize/-cookie/-nsid/-expire.
+type dnsOptionArgs struct {
+	DemoMode            bool
+	IPs                 bool
+	IPv4                bool
+	IPv6                bool
+	MonitorResolverRate bool
+	Unresolved          bool
+	Verbose             bool
+	DNSSEC              bool
+	Subnet              string
+	BufSize             int
+	Cookie              bool
+	NSID                bool
+	Expire              bool
+	QueryStrategy       string
+	NoCache             bool
+	CacheTTLMin         int
+	CacheTTLMax         int
+	WhoisField          string
+}
+
+// ednsOptions translates the -dnssec/-subnet/-bufsize/-cookie/-nsid/-expire
+// flags into the EDNS(0) behavior requested for every query, returning a nil
+// *resolvers.EDNSOptions when none of them were set so the pipeline keeps
+// attaching no OPT record at all.
+func (o dnsOptionArgs) ednsOptions() (*resolvers.EDNSOptions, error) {
+	if !o.DNSSEC && o.Subnet == "" && o.BufSize == 0 && !o.Cookie && !o.NSID && !o.Expire {
+		return nil, nil
+	}
+
+	opts := &resolvers.EDNSOptions{
+		DNSSEC:  o.DNSSEC,
+		BufSize: uint16(o.BufSize),
+		Cookie:  o.Cookie,
+		NSID:    o.NSID,
+		Expire:  o.Expire,
+	}
+
+	if o.Subnet != "" {
+		_, network, err := net.ParseCIDR(o.Subnet)
+		if err != nil {
+			return nil, fmt.Errorf("-subnet: %v", err)
+		}
+		opts.Subnet = network
+	}
+	return opts, nil
+}
+
+// queryStrategy translates the -query-strategy flag into a resolvers.QueryStrategy,
+// defaulting to resolvers.UseIP when the flag was left unset.
+func (o dnsOptionArgs) queryStrategy() (resolvers.QueryStrategy, error) {
+	switch strings.ToLower(o.QueryStrategy) {
+	case "", "useip":
+		return resolvers.UseIP, nil
+	case "useipv4":
+		return resolvers.UseIPv4, nil
+	case "useipv6":
+		return resolvers.UseIPv6, nil
+	case "preferipv4":
+		return resolvers.PreferIPv4, nil
+	case "preferipv6":
+		return resolvers.PreferIPv6, nil
+	}
+	return resolvers.UseIP, fmt.Errorf("-query-strategy: unrecognized strategy: %s", o.QueryStrategy)
+}
+
+// applyResolverOverrides parses each "-resolver-override" entry (addr=value,
+// value a comma-separated list of a CIDR subnet and/or the literal "cookie")
+// and pins the EDNS behavior it describes on pool for that resolver address,
+// regardless of what an individual query requests.
+func applyResolverOverrides(pool *resolvers.ResolverPool, overrides []string) error {
+	for _, o := range overrides {
+		parts := strings.SplitN(o, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("-resolver-override: %q must be addr=value", o)
+		}
+		addr := parts[0]
+
+		opts := &resolvers.EDNSOptions{}
+		for _, tok := range strings.Split(parts[1], ",") {
+			if tok == "cookie" {
+				opts.Cookie = true
+				continue
+			}
+
+			_, network, err := net.ParseCIDR(tok)
+			if err != nil {
+				return fmt.Errorf("-resolver-override: %q: %v", o, err)
+			}
+			opts.Subnet = network
+		}
+
+		pool.SetResolverOverride(addr, opts)
+	}
+	return nil
+}
+
 type dnsArgs struct {
-	Blacklist     stringset.Set
-	Domains       stringset.Set
-	MaxDNSQueries int
-	Names         stringset.Set
-	RecordTypes   stringset.Set
-	Resolvers     stringset.Set
-	Timeout       int
-	Options       struct {
-		DemoMode            bool
-		IPs                 bool
-		IPv4                bool
-		IPv6                bool
-		MonitorResolverRate bool
-		Unresolved          bool
-		Verbose             bool
-	}
-	Filepaths struct {
+	Blacklist         stringset.Set
+	Domains           stringset.Set
+	MaxDNSQueries     int
+	Names             stringset.Set
+	RecordTypes       stringset.Set
+	Resolvers         stringset.Set
+	ResolverOverrides stringset.Set
+	Timeout           int
+	Options           dnsOptionArgs
+	Filepaths         struct {
 		AllFilePrefix string
 		Blacklist     string
+		CacheDir      string
 		ConfigFile    string
 		Directory     string
 		Domains       format.ParseStrings
@@ -56,6 +154,7 @@ type dnsArgs struct {
 		Names         format.ParseStrings
 		Resolvers     format.ParseStrings
 		TermOut       string
+		WhoisOut      string
 	}
 }
 
@@ -64,7 +163,8 @@ func defineDNSArgumentFlags(dnsFlags *flag.FlagSet, args *dnsArgs) {
 	dnsFlags.Var(&args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
 	dnsFlags.IntVar(&args.MaxDNSQueries, "max-dns-queries", 0, "Maximum number of concurrent DNS queries")
 	dnsFlags.Var(&args.RecordTypes, "t", "DNS record types to be queried for (can be used multiple times)")
-	dnsFlags.Var(&args.Resolvers, "r", "IP addresses of preferred DNS resolvers (can be used multiple times)")
+	dnsFlags.Var(&args.Resolvers, "r", "Preferred DNS resolvers: IP addresses, or URIs for DoT/DoH/DoQ/DNSCrypt (can be used multiple times)")
+	dnsFlags.Var(&args.ResolverOverrides, "resolver-override", "Pin the EDNS behavior of one resolver as addr=value, value a comma-separated list of a CIDR subnet and/or the literal \"cookie\" (can be used multiple times)")
 	dnsFlags.IntVar(&args.Timeout, "timeout", 0, "Number of minutes to let enumeration run before quitting")
 }
 
@@ -76,28 +176,42 @@ func defineDNSOptionFlags(dnsFlags *flag.FlagSet, args *dnsArgs) {
 	dnsFlags.BoolVar(&args.Options.MonitorResolverRate, "noresolvrate", true, "Disable resolver rate monitoring")
 	dnsFlags.BoolVar(&args.Options.Unresolved, "include-unresolvable", false, "Output DNS names that did not resolve")
 	dnsFlags.BoolVar(&args.Options.Verbose, "v", false, "Output status / debug / troubleshooting info")
+	dnsFlags.BoolVar(&args.Options.DNSSEC, "dnssec", false, "Set the DNSSEC DO bit and show RRSIG/NSEC/NSEC3/DNSKEY/DS records")
+	dnsFlags.StringVar(&args.Options.Subnet, "subnet", "", "Attach an EDNS Client Subnet option with this network, e.g. 1.2.3.0/24")
+	dnsFlags.IntVar(&args.Options.BufSize, "bufsize", 0, "Advertised EDNS(0) UDP payload size")
+	dnsFlags.BoolVar(&args.Options.Cookie, "cookie", false, "Enable RFC 7873 DNS cookies, caching the server cookie per resolver")
+	dnsFlags.BoolVar(&args.Options.NSID, "nsid", false, "Request the EDNS NSID option and print the identifier returned")
+	dnsFlags.BoolVar(&args.Options.Expire, "expire", false, "Request the EDNS Expire option (RFC 7314)")
+	dnsFlags.StringVar(&args.Options.QueryStrategy, "query-strategy", "", "Address family strategy: UseIP, UseIPv4, UseIPv6, PreferIPv4, or PreferIPv6")
+	dnsFlags.BoolVar(&args.Options.NoCache, "no-cache", false, "Disable the DNS answer cache")
+	dnsFlags.IntVar(&args.Options.CacheTTLMin, "cache-ttl-min", 0, "Minimum number of minutes to honor for any cached answer")
+	dnsFlags.IntVar(&args.Options.CacheTTLMax, "cache-ttl-max", 0, "Maximum number of minutes to cache any answer")
+	dnsFlags.StringVar(&args.Options.WhoisField, "whois-field", "", "Registrant field:term to pivot on for -whois-out instead of the root domains, e.g. email:foo@bar.com")
 }
 
 func defineDNSFilepathFlags(dnsFlags *flag.FlagSet, args *dnsArgs) {
 	dnsFlags.StringVar(&args.Filepaths.AllFilePrefix, "oA", "", "Path prefix used for naming all output files")
 	dnsFlags.StringVar(&args.Filepaths.Blacklist, "blf", "", "Path to a file providing blacklisted subdomains")
+	dnsFlags.StringVar(&args.Filepaths.CacheDir, "cache-dir", "", "Directory holding a persisted DNS answer cache, shareable between runs/hosts")
 	dnsFlags.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
 	dnsFlags.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the output files")
 	dnsFlags.Var(&args.Filepaths.Domains, "df", "Path to a file providing root domain names")
 	dnsFlags.StringVar(&args.Filepaths.JSONOutput, "json", "", "Path to the JSON output file")
 	dnsFlags.StringVar(&args.Filepaths.LogFile, "log", "", "Path to the log file where errors will be written")
 	dnsFlags.Var(&args.Filepaths.Names, "nf", "Path to a file providing already known subdomain names (from other tools/sources)")
-	dnsFlags.Var(&args.Filepaths.Resolvers, "rf", "Path to a file providing preferred DNS resolvers")
+	dnsFlags.Var(&args.Filepaths.Resolvers, "rf", "Path to a file providing preferred DNS resolvers, one IP address or resolver URI per line")
 	dnsFlags.StringVar(&args.Filepaths.TermOut, "o", "", "Path to the text file containing terminal stdout/stderr")
+	dnsFlags.StringVar(&args.Filepaths.WhoisOut, "whois-out", "", "Path to a file that receives domains discovered by pivoting the root domains through reverse-whois, for input to a later enumeration")
 }
 
 func runDNSCommand(clArgs []string) {
 	args := dnsArgs{
-		Blacklist:   stringset.New(),
-		Domains:     stringset.New(),
-		Names:       stringset.New(),
-		RecordTypes: stringset.New(),
-		Resolvers:   stringset.New(),
+		Blacklist:         stringset.New(),
+		Domains:           stringset.New(),
+		Names:             stringset.New(),
+		RecordTypes:       stringset.New(),
+		Resolvers:         stringset.New(),
+		ResolverOverrides: stringset.New(),
 	}
 	var help1, help2 bool
 	dnsCommand := flag.NewFlagSet("dns", flag.ContinueOnError)
@@ -137,6 +251,10 @@ func runDNSCommand(clArgs []string) {
 		if len(cfg.Resolvers) > 0 && len(args.Resolvers) == 0 {
 			args.Resolvers = stringset.New(cfg.Resolvers...)
 		}
+		// Check if a config file was provided that has a query strategy specified
+		if cfg.QueryStrategy != "" && args.Options.QueryStrategy == "" {
+			args.Options.QueryStrategy = cfg.QueryStrategy
+		}
 	} else if args.Filepaths.ConfigFile != "" {
 		r.Fprintf(color.Error, "Failed to load the configuration file: %v\n", err)
 		os.Exit(1)
@@ -159,14 +277,72 @@ func runDNSCommand(clArgs []string) {
 		os.Exit(1)
 	}
 
-	performResolutions(cfg, sys)
+	jsonWriter, err := newDNSJSONWriter(args.Filepaths.JSONOutput)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if jsonWriter != nil {
+		defer jsonWriter.Close(sys.Pool())
+	}
+
+	opts, err := args.Options.ednsOptions()
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+
+	strategy, err := args.Options.queryStrategy()
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	sys.Pool().SetQueryStrategy(strategy)
+
+	if err := applyResolverOverrides(sys.Pool(), args.ResolverOverrides.Slice()); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if !args.Options.NoCache {
+		cache := resolvers.NewAnswerCache(resolvers.CacheConfig{
+			Dir:    args.Filepaths.CacheDir,
+			MinTTL: time.Duration(args.Options.CacheTTLMin) * time.Minute,
+			MaxTTL: time.Duration(args.Options.CacheTTLMax) * time.Minute,
+		})
+		sys.Pool().SetCache(cache)
+		defer cache.Save()
+	}
+
+	if args.Filepaths.WhoisOut != "" {
+		var field sources.AdvancedWhoisField
+		var term string
+		if args.Options.WhoisField != "" {
+			field, term, err = sources.ParseWhoisField(args.Options.WhoisField)
+			if err != nil {
+				r.Fprintf(color.Error, "%v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if err := runReverseWhois(cfg, sys.Pool(), args.Filepaths.WhoisOut, field, term); err != nil {
+			r.Fprintf(color.Error, "%v\n", err)
+			os.Exit(1)
+		}
+	} else if args.Options.WhoisField != "" {
+		r.Fprintf(color.Error, "-whois-field requires -whois-out\n")
+		os.Exit(1)
+	}
+
+	performResolutions(cfg, sys, opts, strategy, jsonWriter)
 }
 
-func performResolutions(cfg *config.Config, sys services.System) {
+func performResolutions(cfg *config.Config, sys services.System, opts *resolvers.EDNSOptions,
+	strategy resolvers.QueryStrategy, jsonWriter *dnsJSONWriter) {
 	done := make(chan struct{})
 	active := make(chan struct{}, 1000000)
 	bus := eventbus.NewEventBus(10000)
-	answers := make(chan *requests.DNSRequest, 100000)
+	answers := make(chan *dnsResult, 100000)
 
 	// Setup the context used throughout the resolutions
 	ctx, cancel := context.WithCancel(context.Background())
@@ -185,6 +361,12 @@ func performResolutions(cfg *config.Config, sys services.System) {
 	bus.Subscribe(requests.ResolveCompleted, resolvFunc)
 	defer bus.Unsubscribe(requests.ResolveCompleted, resolvFunc)
 
+	if opts != nil && opts.NSID {
+		logFunc := func(msg string) { fmt.Fprintf(color.Output, "%s\n", yellow(msg)) }
+		bus.Subscribe(requests.LogTopic, logFunc)
+		defer bus.Unsubscribe(requests.LogTopic, logFunc)
+	}
+
 	go func() {
 		for _, name := range cfg.ProvidedNames {
 			select {
@@ -193,16 +375,25 @@ func performResolutions(cfg *config.Config, sys services.System) {
 				return
 			default:
 				cfg.SemMaxDNSQueries.Acquire(1)
-				go processDNSRequest(ctx, &requests.DNSRequest{Name: name}, cfg, sys, answers)
+				go processDNSRequest(ctx, &requests.DNSRequest{Name: name}, cfg, sys, opts, strategy, answers)
 			}
 		}
 	}()
 
-	processDNSAnswers(cfg, active, answers, done)
+	processDNSAnswers(cfg, active, answers, done, jsonWriter)
+}
+
+// dnsResult pairs a resolved requests.DNSRequest with the per-query metadata
+// (resolver used, latency, rcode, header flags) that -json streams out but
+// requests.DNSRequest itself has no field for. processDNSRequest sends a nil
+// *dnsResult when the name didn't resolve.
+type dnsResult struct {
+	req    *requests.DNSRequest
+	latest *resolvers.EDNSInfo
 }
 
-func processDNSRequest(ctx context.Context, req *requests.DNSRequest,
-	cfg *config.Config, sys services.System, c chan *requests.DNSRequest) {
+func processDNSRequest(ctx context.Context, req *requests.DNSRequest, cfg *config.Config, sys services.System,
+	opts *resolvers.EDNSOptions, strategy resolvers.QueryStrategy, c chan *dnsResult) {
 	defer cfg.SemMaxDNSQueries.Release(1)
 
 	if req == nil || req.Name == "" {
@@ -221,29 +412,231 @@ func processDNSRequest(ctx context.Context, req *requests.DNSRequest,
 		return
 	}
 
+	bus := ctx.Value(requests.ContextEventBus).(*eventbus.EventBus)
+
 	var answers []requests.DNSAnswer
+	var latest *resolvers.EDNSInfo
 	for _, t := range cfg.RecordTypes {
-		a, _, err := sys.Pool().Resolve(ctx, req.Name, t, resolvers.PriorityLow)
+		if excludedByStrategy(t, strategy) {
+			continue
+		}
+
+		a, _, info, err := sys.Pool().Resolve(ctx, req.Name, t, resolvers.PriorityLow, opts)
 		if err == nil {
 			answers = append(answers, a...)
+			if info != nil {
+				latest = info
+			}
+		}
+		if opts != nil && opts.NSID && info != nil && info.NSID != "" {
+			bus.Publish(requests.LogTopic, fmt.Sprintf("%s: NSID: %s", req.Name, info.NSID))
 		}
 
 		if t == "CNAME" && len(answers) > 0 {
 			break
 		}
 	}
-	req.Records = answers
+	req.Records = preferredFamily(answers, strategy)
 
 	if len(req.Records) == 0 || sys.Pool().MatchesWildcard(ctx, req) {
 		c <- nil
 		return
 	}
 
-	c <- req
+	c <- &dnsResult{req: req, latest: latest}
+}
+
+// excludedByStrategy reports whether record type t must never be queried
+// under strategy, mirroring resolvers.ResolverPool's own wire-level filtering
+// so a disabled family is skipped before a query is even attempted.
+func excludedByStrategy(t string, strategy resolvers.QueryStrategy) bool {
+	switch strategy {
+	case resolvers.UseIPv4:
+		return t == "AAAA"
+	case resolvers.UseIPv6:
+		return t == "A"
+	}
+	return false
+}
+
+// preferredFamily drops the non-preferred address family from answers when
+// strategy is PreferIPv4 or PreferIPv6 and the preferred family produced at
+// least one answer; every other strategy returns answers unchanged.
+func preferredFamily(answers []requests.DNSAnswer, strategy resolvers.QueryStrategy) []requests.DNSAnswer {
+	var preferred, drop uint16
+	switch strategy {
+	case resolvers.PreferIPv4:
+		preferred, drop = dns.TypeA, dns.TypeAAAA
+	case resolvers.PreferIPv6:
+		preferred, drop = dns.TypeAAAA, dns.TypeA
+	default:
+		return answers
+	}
+
+	hasPreferred := false
+	for _, a := range answers {
+		if uint16(a.Type) == preferred {
+			hasPreferred = true
+			break
+		}
+	}
+	if !hasPreferred {
+		return answers
+	}
+
+	filtered := answers[:0]
+	for _, a := range answers {
+		if uint16(a.Type) != drop {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+// jsonDNSAnswer is the per-record portion of a jsonDNSRecord line, using the
+// symbolic record type name rather than its numeric dns.Type value.
+type jsonDNSAnswer struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// jsonDNSRecord is one line of the -json NDJSON stream: everything resolved
+// for a single name, plus the resolver metadata processDNSRequest gathered
+// along the way.
+type jsonDNSRecord struct {
+	Name         string          `json:"name"`
+	Domain       string          `json:"domain"`
+	Records      []jsonDNSAnswer `json:"records"`
+	ResolverUsed string          `json:"resolver_used,omitempty"`
+	LatencyMS    int64           `json:"latency_ms,omitempty"`
+	Rcode        int             `json:"rcode"`
+	Flags        string          `json:"flags,omitempty"`
+	EDNS         *jsonDNSEDNS    `json:"edns,omitempty"`
+}
+
+// jsonDNSEDNS carries the NSID and the negative-caching TTL (SOA MINIMUM)
+// extractEDNSInfo surfaced for the query, included whenever either is set.
+type jsonDNSEDNS struct {
+	NSID   string `json:"nsid,omitempty"`
+	NegTTL uint32 `json:"neg_ttl,omitempty"`
+}
+
+// jsonDNSSummary is the final object the -json stream emits, letting
+// downstream tooling see per-type and per-resolver totals without tallying
+// the preceding NDJSON lines itself.
+type jsonDNSSummary struct {
+	RecordTypeCounts   map[string]int     `json:"record_type_counts"`
+	ResolverSuccessPct map[string]float64 `json:"resolver_success_pct"`
+}
+
+// dnsJSONWriter streams one jsonDNSRecord per resolved name to an NDJSON
+// file, tracking the per-type counts needed for the closing jsonDNSSummary.
+// A nil *dnsJSONWriter is valid and every method on it is a no-op, so callers
+// don't need to guard every call behind "-json was set".
+type dnsJSONWriter struct {
+	mu         sync.Mutex
+	f          *os.File
+	enc        *json.Encoder
+	typeCounts map[string]int
+}
+
+// newDNSJSONWriter opens path for the -json NDJSON stream. It returns a nil
+// *dnsJSONWriter, without error, when path is empty.
+func newDNSJSONWriter(path string) (*dnsJSONWriter, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the JSON output file: %v", err)
+	}
+
+	return &dnsJSONWriter{
+		f:          f,
+		enc:        json.NewEncoder(f),
+		typeCounts: make(map[string]int),
+	}, nil
+}
+
+// WriteRecord emits one NDJSON line for req, drawing the resolver/latency/
+// rcode/flags/OPT fields from info, the metadata gathered for the last
+// record type that resolved successfully.
+func (w *dnsJSONWriter) WriteRecord(req *requests.DNSRequest, info *resolvers.EDNSInfo) {
+	if w == nil {
+		return
+	}
+
+	rec := jsonDNSRecord{Name: req.Name, Domain: req.Domain}
+	for _, a := range req.Records {
+		name := typeToName(uint16(a.Type))
+		rec.Records = append(rec.Records, jsonDNSAnswer{Type: name, Data: resolvers.RemoveLastDot(a.Data)})
+	}
+	if info != nil {
+		rec.ResolverUsed = info.ResolverAddr
+		rec.LatencyMS = info.Latency.Milliseconds()
+		rec.Rcode = info.Rcode
+		rec.Flags = ednsFlagString(info)
+		if info.NSID != "" || info.NegTTL != 0 {
+			rec.EDNS = &jsonDNSEDNS{NSID: info.NSID, NegTTL: info.NegTTL}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, a := range rec.Records {
+		w.typeCounts[a.Type]++
+	}
+	w.enc.Encode(&rec)
+}
+
+// ednsFlagString renders the AA/TC/AD/CD header bits info carries as a
+// compact, space-separated flag string, e.g. "AA AD".
+func ednsFlagString(info *resolvers.EDNSInfo) string {
+	var flags []string
+	if info.AA {
+		flags = append(flags, "AA")
+	}
+	if info.TC {
+		flags = append(flags, "TC")
+	}
+	if info.AD {
+		flags = append(flags, "AD")
+	}
+	if info.CD {
+		flags = append(flags, "CD")
+	}
+	return strings.Join(flags, " ")
+}
+
+// Close writes the closing jsonDNSSummary and the file, pulling per-resolver
+// success rates from pool's rate monitor.
+func (w *dnsJSONWriter) Close(pool *resolvers.ResolverPool) error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	summary := jsonDNSSummary{
+		RecordTypeCounts:   w.typeCounts,
+		ResolverSuccessPct: make(map[string]float64),
+	}
+	for addr, stats := range pool.Stats() {
+		if stats.Queries == 0 {
+			continue
+		}
+		summary.ResolverSuccessPct[addr] = 100 * float64(stats.Queries-stats.Errors) / float64(stats.Queries)
+	}
+	w.enc.Encode(&summary)
+
+	return w.f.Close()
 }
 
-func processDNSAnswers(cfg *config.Config,
-	activeChan chan struct{}, answers chan *requests.DNSRequest, done chan struct{}) {
+func processDNSAnswers(cfg *config.Config, activeChan chan struct{},
+	answers chan *dnsResult, done chan struct{}, jsonWriter *dnsJSONWriter) {
 	first := true
 	active := true
 
@@ -265,12 +658,13 @@ func processDNSAnswers(cfg *config.Config,
 			return
 		case <-activeChan:
 			active = true
-		case req := <-answers:
+		case result := <-answers:
 			i++
 			active = true
 			first = false
 
-			if req != nil && len(req.Records) != 0 {
+			if result != nil && result.req != nil && len(result.req.Records) != 0 {
+				req := result.req
 				tss := stringset.New()
 				for _, rec := range req.Records {
 					tss.Insert(typeToName(uint16(rec.Type)))
@@ -291,13 +685,12 @@ func processDNSAnswers(cfg *config.Config,
 						data += ", "
 					}
 
-					if uint16(rec.Type) == dns.TypeNS {
-						rec.Data = strings.Split(rec.Data, ",")[1]
-					}
 					data += resolvers.RemoveLastDot(rec.Data)
 				}
 
 				fmt.Fprintf(color.Output, "%s%s %s\n", blue(tstr), green(req.Name), yellow(data))
+
+				jsonWriter.WriteRecord(req, result.latest)
 			}
 		}
 	}
@@ -387,6 +780,9 @@ func (d dnsArgs) OverrideConfig(conf *config.Config) error {
 	if !d.Options.MonitorResolverRate {
 		conf.MonitorResolverRate = false
 	}
+	if d.Options.QueryStrategy != "" {
+		conf.QueryStrategy = d.Options.QueryStrategy
+	}
 
 	// Attempt to add the provided domains to the configuration
 	conf.AddDomains(d.Domains.Slice())
@@ -417,6 +813,16 @@ func typeToName(qtype uint16) string {
 		name = "SPF"
 	case dns.TypeSRV:
 		name = "SRV"
+	case dns.TypeRRSIG:
+		name = "RRSIG"
+	case dns.TypeNSEC:
+		name = "NSEC"
+	case dns.TypeNSEC3:
+		name = "NSEC3"
+	case dns.TypeDNSKEY:
+		name = "DNSKEY"
+	case dns.TypeDS:
+		name = "DS"
 	}
 
 	return name
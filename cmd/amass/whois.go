@@ -0,0 +1,82 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/resolvers"
+	"github.com/OWASP/Amass/v3/services/sources"
+)
+
+// whoisProviderSettleTime bounds how long runReverseWhois waits for the
+// providers to answer every domain, derived from the 10-second per-provider
+// rate limit each source in services/sources applies to itself.
+const whoisProviderSettleTime = 11 * time.Second
+
+// runReverseWhois pivots every one of cfg's root domains through each
+// configured reverse-whois provider (WhoisXML, ViewDNS, DomainTools,
+// SecurityTrails, WhoisFreaks) and writes every newly discovered domain to
+// outPath, one per line, so the results can be fed back into a later
+// "amass dns -df" enumeration. When field is non-empty, every provider except
+// ViewDNS pivots on field:term (a registrant email/org/ns/mx, per
+// -whois-field) instead of the plain domain; ViewDNS still pivots on the domain.
+func runReverseWhois(cfg *config.Config, pool *resolvers.ResolverPool, outPath string, field sources.AdvancedWhoisField, term string) error {
+	domains := cfg.Domains()
+	if len(domains) == 0 {
+		return nil
+	}
+
+	bus := eventbus.NewEventBus(1000)
+
+	providers := []interface{ OnStart() error }{
+		sources.NewWhoisXML(cfg, bus, pool),
+		sources.NewViewDNS(cfg, bus, pool),
+		sources.NewDomainTools(cfg, bus, pool),
+		sources.NewSecurityTrails(cfg, bus, pool),
+		sources.NewWhoisFreaks(cfg, bus, pool),
+	}
+	for _, p := range providers {
+		p.OnStart()
+	}
+
+	found := make(chan []string, len(domains)*len(providers))
+	collect := func(req *requests.WhoisRequest) { found <- req.NewDomains }
+	bus.Subscribe(requests.NewWhoisTopic, collect)
+	defer bus.Unsubscribe(requests.NewWhoisTopic, collect)
+
+	for _, d := range domains {
+		bus.Publish(requests.WhoisRequestTopic, &requests.WhoisRequest{
+			Domain: d,
+			Field:  string(field),
+			Term:   term,
+		})
+	}
+
+	time.Sleep(time.Duration(len(domains)) * whoisProviderSettleTime)
+	close(found)
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create the whois output file: %v", err)
+	}
+	defer f.Close()
+
+	seen := make(map[string]struct{})
+	for newDomains := range found {
+		for _, d := range newDomains {
+			if _, dup := seen[d]; dup {
+				continue
+			}
+			seen[d] = struct{}{}
+			fmt.Fprintln(f, d)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// cacheFileName is the snapshot persisted under CacheConfig.Dir, letting a
+// warmed cache be reused by a later run or shared with another host.
+const cacheFileName = "dns_answer_cache.gob"
+
+// CacheConfig controls how an AnswerCache stores and expires entries.
+type CacheConfig struct {
+	// Dir, when non-empty, is the directory an AnswerCache loads its
+	// snapshot from and persists it back to on Save.
+	Dir string
+
+	// MinTTL and MaxTTL clamp every TTL the cache honors, whether it came
+	// from an RRset's own TTL or the SOA MINIMUM used for negative caching.
+	// Either may be left zero to leave that bound unclamped.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+}
+
+// cacheEntry is the unit persisted to and loaded from the cache snapshot.
+type cacheEntry struct {
+	Answers  []requests.DNSAnswer
+	NXDomain bool
+	Expires  time.Time
+}
+
+// AnswerCache remembers previously obtained DNS answers, keyed by name and
+// query type, so repeated enumerations of the same target set don't
+// re-query resolvers for records that haven't expired yet. Both positive
+// answers and negative (NXDOMAIN/NODATA) results are cached, each expiring
+// according to the TTL carried in the response: the smallest RRset TTL for
+// an answer, or the authority section's SOA MINIMUM (RFC 2308) for a
+// negative result.
+type AnswerCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	cfg     CacheConfig
+}
+
+// NewAnswerCache builds an AnswerCache from cfg, loading any snapshot
+// previously persisted to cfg.Dir. A zero-value cfg yields an in-memory-only
+// cache that starts empty and is never persisted.
+func NewAnswerCache(cfg CacheConfig) *AnswerCache {
+	c := &AnswerCache{
+		entries: make(map[string]*cacheEntry),
+		cfg:     cfg,
+	}
+	c.load()
+	return c
+}
+
+// cacheKey identifies a cached entry by name and query type.
+func cacheKey(name, qtype string) string {
+	return strings.ToLower(RemoveLastDot(name)) + " " + qtype
+}
+
+// Get returns the cached answers for name/qtype, if a live entry exists.
+func (c *AnswerCache) Get(name, qtype string) (answers []requests.DNSAnswer, nxdomain bool, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[cacheKey(name, qtype)]
+	if !ok || time.Now().After(e.Expires) {
+		return nil, false, false
+	}
+	return e.Answers, e.NXDomain, true
+}
+
+// Store records answers for name/qtype. The entry expires after the
+// smallest TTL among answers, or after negTTL when nxdomain is true or
+// answers is empty; either is clamped to cfg.MinTTL/cfg.MaxTTL. A result
+// that would expire immediately (ttl <= 0) is not cached.
+func (c *AnswerCache) Store(name, qtype string, answers []requests.DNSAnswer, nxdomain bool, negTTL uint32) {
+	ttl := c.ttlFor(answers, nxdomain, negTTL)
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cacheKey(name, qtype)] = &cacheEntry{
+		Answers:  answers,
+		NXDomain: nxdomain,
+		Expires:  time.Now().Add(ttl),
+	}
+}
+
+// ttlFor derives the caching duration for a Store call, honoring cfg.MinTTL/MaxTTL.
+func (c *AnswerCache) ttlFor(answers []requests.DNSAnswer, nxdomain bool, negTTL uint32) time.Duration {
+	var ttl time.Duration
+
+	if nxdomain || len(answers) == 0 {
+		ttl = time.Duration(negTTL) * time.Second
+	} else {
+		min := answers[0].TTL
+		for _, a := range answers[1:] {
+			if a.TTL < min {
+				min = a.TTL
+			}
+		}
+		ttl = time.Duration(min) * time.Second
+	}
+
+	if c.cfg.MinTTL > 0 && ttl < c.cfg.MinTTL {
+		ttl = c.cfg.MinTTL
+	}
+	if c.cfg.MaxTTL > 0 && ttl > c.cfg.MaxTTL {
+		ttl = c.cfg.MaxTTL
+	}
+	return ttl
+}
+
+// Save persists the still-live entries to cfg.Dir as a gob snapshot. It is a
+// no-op when cfg.Dir is empty.
+func (c *AnswerCache) Save() error {
+	if c.cfg.Dir == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	live := make(map[string]*cacheEntry, len(c.entries))
+	for k, e := range c.entries {
+		if now.Before(e.Expires) {
+			live[k] = e
+		}
+	}
+
+	if err := os.MkdirAll(c.cfg.Dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(c.cfg.Dir, cacheFileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return gob.NewEncoder(f).Encode(live)
+}
+
+// load populates the cache from any snapshot found in cfg.Dir. It leaves the
+// cache empty, without error, when cfg.Dir is unset or holds no snapshot yet.
+func (c *AnswerCache) load() {
+	if c.cfg.Dir == "" {
+		return
+	}
+
+	f, err := os.Open(filepath.Join(c.cfg.Dir, cacheFileName))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var entries map[string]*cacheEntry
+	if err := gob.NewDecoder(f).Decode(&entries); err != nil {
+		return
+	}
+	c.entries = entries
+}
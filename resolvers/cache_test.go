@@ -0,0 +1,76 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// TestAnswerCacheExpiry confirms a stored entry is served until its TTL
+// elapses, then treated as a miss.
+func TestAnswerCacheExpiry(t *testing.T) {
+	c := NewAnswerCache(CacheConfig{})
+
+	answers := []requests.DNSAnswer{{Name: "www.example.com", Type: 1, TTL: 1}}
+	c.Store("www.example.com", "A", answers, false, 0)
+
+	got, nxdomain, found := c.Get("www.example.com", "A")
+	if !found || nxdomain || len(got) != 1 {
+		t.Fatalf("Get returned found=%v nxdomain=%v answers=%+v, want a live hit", found, nxdomain, got)
+	}
+
+	c.entries[cacheKey("www.example.com", "A")].Expires = time.Now().Add(-time.Second)
+
+	if _, _, found := c.Get("www.example.com", "A"); found {
+		t.Fatalf("Get returned a hit for an entry past its TTL")
+	}
+}
+
+// TestAnswerCacheMinMaxTTL confirms cfg.MinTTL/MaxTTL clamp the TTL a Store
+// call would otherwise use.
+func TestAnswerCacheMinMaxTTL(t *testing.T) {
+	c := NewAnswerCache(CacheConfig{MinTTL: 10 * time.Minute, MaxTTL: 20 * time.Minute})
+
+	short := []requests.DNSAnswer{{TTL: 1}}
+	c.Store("short.example.com", "A", short, false, 0)
+	e := c.entries[cacheKey("short.example.com", "A")]
+	if d := time.Until(e.Expires); d < 9*time.Minute || d > 10*time.Minute {
+		t.Fatalf("MinTTL clamp not applied: entry expires in %v", d)
+	}
+
+	long := []requests.DNSAnswer{{TTL: 3600}}
+	c.Store("long.example.com", "A", long, false, 0)
+	e = c.entries[cacheKey("long.example.com", "A")]
+	if d := time.Until(e.Expires); d < 19*time.Minute || d > 20*time.Minute {
+		t.Fatalf("MaxTTL clamp not applied: entry expires in %v", d)
+	}
+}
+
+// TestAnswerCacheNegativeNotCachedWithoutTTL confirms an NXDOMAIN result
+// with no negative TTL is not cached, since it would expire immediately.
+func TestAnswerCacheNegativeNotCachedWithoutTTL(t *testing.T) {
+	c := NewAnswerCache(CacheConfig{})
+
+	c.Store("missing.example.com", "A", nil, true, 0)
+
+	if _, _, found := c.Get("missing.example.com", "A"); found {
+		t.Fatalf("Get returned a hit for a negative result cached with a zero TTL")
+	}
+}
+
+// TestAnswerCacheNegativeTTL confirms an NXDOMAIN result is cached under
+// negTTL and reported back as nxdomain with no answers.
+func TestAnswerCacheNegativeTTL(t *testing.T) {
+	c := NewAnswerCache(CacheConfig{})
+
+	c.Store("missing.example.com", "A", nil, true, 60)
+
+	answers, nxdomain, found := c.Get("missing.example.com", "A")
+	if !found || !nxdomain || len(answers) != 0 {
+		t.Fatalf("Get returned found=%v nxdomain=%v answers=%+v, want a negative hit", found, nxdomain, answers)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+// queryMessage builds the outgoing dns.Msg for name/qtype, shared by every
+// transport. When opts is non-nil, the requested OPT pseudo-record is attached,
+// consulting cookies (keyed on addr) to present back any cached server cookie.
+func queryMessage(name string, qtype uint16, opts *EDNSOptions, addr string, cookies *cookieCache) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn(name), qtype)
+	msg.RecursionDesired = true
+	msg.Id = dns.Id()
+
+	attachEDNS(msg, opts, addr, cookies)
+	return msg
+}
+
+// extractAnswers converts the RRs in resp into the transport-agnostic requests.DNSAnswer type.
+func extractAnswers(resp *dns.Msg) []requests.DNSAnswer {
+	if resp == nil {
+		return nil
+	}
+
+	var answers []requests.DNSAnswer
+	for _, rr := range resp.Answer {
+		hdr := rr.Header()
+
+		answers = append(answers, requests.DNSAnswer{
+			Name: RemoveLastDot(hdr.Name),
+			Type: int(hdr.Rrtype),
+			TTL:  int(hdr.Ttl),
+			Data: RemoveLastDot(dataFromRR(rr)),
+		})
+	}
+	return answers
+}
+
+// dataFromRR pulls the answer-specific portion out of the RR's text representation.
+func dataFromRR(rr dns.RR) string {
+	full := rr.String()
+	hdr := rr.Header().String()
+
+	if len(full) > len(hdr) {
+		return full[len(hdr):]
+	}
+	return full
+}
@@ -0,0 +1,343 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// DNSCryptResolver queries a resolver speaking the DNSCrypt protocol: the resolver's
+// certificate is fetched once over an unauthenticated query, then every
+// subsequent query/response pair is encrypted with XChaCha20-Poly1305 using a
+// shared key derived from an ephemeral X25519 key pair (RFC-less, per the
+// DNSCrypt v2 protocol spec).
+type DNSCryptResolver struct {
+	sync.Mutex
+
+	stamp    *dnsCryptStamp
+	udp      *UDPResolver
+	serverPK [32]byte
+	haveCert bool
+	stopped  bool
+}
+
+// NewDNSCryptResolver decodes the sdns:// stamp and initializes a resolver that
+// will negotiate a certificate with the server on the first query.
+func NewDNSCryptResolver(stamp string) (*DNSCryptResolver, error) {
+	s, err := parseDNSCryptStamp(stamp)
+	if err != nil {
+		return nil, err
+	}
+
+	udp, err := NewUDPResolver(s.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DNSCryptResolver{
+		stamp: s,
+		udp:   udp,
+	}, nil
+}
+
+// Address implements the Resolver interface.
+func (r *DNSCryptResolver) Address() string {
+	return r.stamp.Addr
+}
+
+// Stop implements the Resolver interface.
+func (r *DNSCryptResolver) Stop() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+	return r.udp.Stop()
+}
+
+// Stopped implements the Resolver interface.
+func (r *DNSCryptResolver) Stopped() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stopped
+}
+
+// negotiateCertificate performs the DNSCrypt certificate exchange: a plain TXT
+// query for the provider name returns a signed certificate containing the
+// server's short-term public key used for the encrypted session.
+func (r *DNSCryptResolver) negotiateCertificate(ctx context.Context) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.haveCert {
+		return nil
+	}
+
+	certs, err := r.queryCertificates(ctx)
+	if err != nil {
+		return fmt.Errorf("dnscrypt certificate exchange with %s failed: %v", r.stamp.Addr, err)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("dnscrypt provider %s returned no certificates", r.stamp.ProviderName)
+	}
+
+	pk, err := verifyCertificate(certs, r.stamp.ProviderPK)
+	if err != nil {
+		return fmt.Errorf("dnscrypt certificate validation for %s failed: %v", r.stamp.Addr, err)
+	}
+
+	r.serverPK = pk
+	r.haveCert = true
+	return nil
+}
+
+// queryCertificates issues a plain TXT query for the DNSCrypt provider name
+// and returns the raw RDATA of every TXT answer. It deliberately bypasses
+// the shared extractAnswers/dataFromRR path the rest of the pool uses: that
+// path renders each RR through its zone-file presentation form (quoted and
+// \DDD-escaped), which is the right shape for a human-readable DNSAnswer but
+// corrupts a binary certificate. miekg/dns keeps a TXT record's untouched
+// character-strings in the RR's Txt field, so reading that directly is the
+// only way to get the certificate back byte-for-byte.
+func (r *DNSCryptResolver) queryCertificates(ctx context.Context) ([][]byte, error) {
+	msg := queryMessage(r.stamp.ProviderName, dns.TypeTXT, nil, r.stamp.Addr, nil)
+
+	client := &dns.Client{Net: "udp", Timeout: 3 * time.Second}
+	resp, _, err := client.ExchangeContext(ctx, msg, r.stamp.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return rawTXTAnswers(resp), nil
+}
+
+// rawTXTAnswers returns the raw, unescaped RDATA of every TXT RR in resp's
+// answer section, concatenating the character-strings of each record. A TXT
+// record splits payloads longer than 255 bytes across several
+// character-strings, which must be rejoined to recover the original bytes.
+func rawTXTAnswers(resp *dns.Msg) [][]byte {
+	var out [][]byte
+
+	for _, rr := range resp.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+
+		var raw []byte
+		for _, seg := range txt.Txt {
+			raw = append(raw, []byte(seg)...)
+		}
+		out = append(out, raw)
+	}
+	return out
+}
+
+// Resolve implements the Resolver interface.
+func (r *DNSCryptResolver) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	if r.Stopped() {
+		return nil, false, nil, ErrResolverStopped
+	}
+
+	if err := r.negotiateCertificate(ctx); err != nil {
+		return nil, false, nil, err
+	}
+
+	t, ok := dns.StringToType[qtype]
+	if !ok {
+		return nil, false, nil, fmt.Errorf("unsupported query type: %s", qtype)
+	}
+
+	wire, err := queryMessage(name, t, opts, r.stamp.Addr, nil).Pack()
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	sealed, clientSK, nonce, err := r.encrypt(wire)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", r.stamp.Addr, 3*time.Second)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	if _, err := conn.Write(sealed); err != nil {
+		return nil, false, nil, err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	plain, err := r.decrypt(buf[:n], clientSK, nonce)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(plain); err != nil {
+		return nil, false, nil, err
+	}
+
+	return extractAnswers(resp), resp.Rcode == dns.RcodeNameError, extractEDNSInfo(resp), nil
+}
+
+// encrypt derives an ephemeral X25519 key pair, seals msg with XChaCha20-Poly1305
+// under the key shared with the server's certificate public key, and returns the
+// wire-format DNSCrypt query alongside the values the response decryption needs.
+// clientSK is the private scalar, not the public key: decrypt must redo the same
+// ECDH computation it did here, which only the private half can reproduce.
+func (r *DNSCryptResolver) encrypt(msg []byte) (sealed, clientSK, nonce []byte, err error) {
+	var sk [32]byte
+	if _, err = rand.Read(sk[:]); err != nil {
+		return nil, nil, nil, err
+	}
+
+	pub, err := curve25519.X25519(sk[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	shared, err := curve25519.X25519(sk[:], r.serverPK[:])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(shared)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	nonce = make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, nil, err
+	}
+
+	ciphertext := aead.Seal(nil, nonce, msg, nil)
+
+	out := make([]byte, 0, len(pub)+len(nonce)+len(ciphertext))
+	out = append(out, pub...)
+	out = append(out, nonce...)
+	out = append(out, ciphertext...)
+
+	return out, sk[:], nonce, nil
+}
+
+// decrypt opens the server's response using the same shared key derived in
+// encrypt, recomputing it from clientSK (the private scalar encrypt generated)
+// and r.serverPK.
+func (r *DNSCryptResolver) decrypt(resp, clientSK, nonce []byte) ([]byte, error) {
+	shared, err := curve25519.X25519(clientSK, r.serverPK[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(shared)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp) < len(nonce) {
+		return nil, fmt.Errorf("dnscrypt response shorter than the nonce")
+	}
+
+	return aead.Open(nil, nonce, resp[len(nonce):], nil)
+}
+
+// Certificate layout, per the DNSCrypt v2 protocol spec
+// (https://dnscrypt.info/protocol):
+//
+//	cert-magic(4) es-version(2) minor-version(2) signature(64)
+//	resolver-pk(32) client-magic(8) serial(4) ts-start(4) ts-end(4) [extensions]
+//
+// The signature covers everything from resolver-pk through the end of the
+// certificate (including any extensions) and must verify against the
+// stamp's long-term provider public key before the resolver-pk it carries
+// is trusted for the encrypted session.
+const (
+	certMagicSize      = 4
+	certHeaderSize     = certMagicSize + 2 + 2 // magic + es-version + minor-version
+	certSignatureSize  = ed25519.SignatureSize
+	certSignedOffset   = certHeaderSize + certSignatureSize
+	certFixedSignedLen = 32 + 8 + 4 + 4 + 4 // resolver-pk + client-magic + serial + ts-start + ts-end
+	certMinSize        = certSignedOffset + certFixedSignedLen
+
+	// esVersionXChaCha20Poly1305 is the only cipher construction this
+	// resolver implements; a certificate advertising any other es-version
+	// is rejected rather than silently used.
+	esVersionXChaCha20Poly1305 = 2
+)
+
+var certMagic = [certMagicSize]byte{'D', 'N', 'S', 'C'}
+
+// verifyCertificate picks the newest currently-valid certificate among certs
+// whose Ed25519 signature checks out against providerPK, returning the
+// resolver short-term public key it authorizes. Certificates that are
+// malformed, signed with an unsupported es-version, outside their validity
+// window, or not signed by providerPK are ignored. certs holds the raw bytes
+// of each TXT answer's RDATA, not its presentation-form string.
+func verifyCertificate(certs [][]byte, providerPK []byte) ([32]byte, error) {
+	var best [32]byte
+
+	if len(providerPK) != ed25519.PublicKeySize {
+		return best, fmt.Errorf("invalid dnscrypt provider public key length: %d", len(providerPK))
+	}
+
+	var bestSerial uint32
+	found := false
+	now := uint32(time.Now().Unix())
+
+	for _, data := range certs {
+		if len(data) < certMinSize || !bytes.Equal(data[:certMagicSize], certMagic[:]) {
+			continue
+		}
+		if binary.BigEndian.Uint16(data[certMagicSize:certHeaderSize]) != esVersionXChaCha20Poly1305 {
+			continue
+		}
+
+		signature := data[certHeaderSize:certSignedOffset]
+		signed := data[certSignedOffset:]
+		if !ed25519.Verify(providerPK, signed, signature) {
+			continue
+		}
+
+		serial := binary.BigEndian.Uint32(signed[40:44])
+		tsStart := binary.BigEndian.Uint32(signed[44:48])
+		tsEnd := binary.BigEndian.Uint32(signed[48:52])
+		if now < tsStart || now > tsEnd {
+			continue
+		}
+		if found && serial <= bestSerial {
+			continue
+		}
+
+		copy(best[:], signed[:32])
+		bestSerial = serial
+		found = true
+	}
+
+	if !found {
+		return best, fmt.Errorf("no validly signed, currently valid dnscrypt certificate was found")
+	}
+	return best, nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// txtCharacterStrings splits data into the <=255-byte character-strings a
+// real DNS server packs a TXT RDATA into.
+func txtCharacterStrings(data []byte) []string {
+	var segs []string
+	for len(data) > 0 {
+		n := len(data)
+		if n > 255 {
+			n = 255
+		}
+		segs = append(segs, string(data[:n]))
+		data = data[n:]
+	}
+	return segs
+}
+
+// TestDNSCryptEncryptDecryptRoundTrip confirms that decrypt can open a
+// response sealed under the same shared secret encrypt derived, exercising
+// the full client-scalar/server-key ECDH on both sides.
+func TestDNSCryptEncryptDecryptRoundTrip(t *testing.T) {
+	var serverSK [32]byte
+	if _, err := rand.Read(serverSK[:]); err != nil {
+		t.Fatalf("failed to generate server key: %v", err)
+	}
+	serverPK, err := curve25519.X25519(serverSK[:], curve25519.Basepoint)
+	if err != nil {
+		t.Fatalf("failed to derive server public key: %v", err)
+	}
+
+	r := &DNSCryptResolver{}
+	copy(r.serverPK[:], serverPK)
+
+	query := []byte("round trip query")
+	sealed, clientSK, nonce, err := r.encrypt(query)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	clientPK := sealed[:32]
+	shared, err := curve25519.X25519(serverSK[:], clientPK)
+	if err != nil {
+		t.Fatalf("server-side ECDH failed: %v", err)
+	}
+	aead, err := chacha20poly1305.NewX(shared)
+	if err != nil {
+		t.Fatalf("failed to build the server-side AEAD: %v", err)
+	}
+
+	want := []byte("round trip response")
+	ciphertext := aead.Seal(nil, nonce, want, nil)
+
+	// decrypt only uses the length of nonce to find where the ciphertext
+	// begins in the wire response, not its leading bytes, so any prefix of
+	// that length stands in for the bytes a real server reply would carry.
+	wire := append(make([]byte, len(nonce)), ciphertext...)
+
+	got, err := r.decrypt(wire, clientSK, nonce)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("decrypt returned %q, want %q", got, want)
+	}
+}
+
+// TestDNSCryptEncryptDecryptWrongKeyFails confirms decrypt cannot open a
+// response sealed under a different server's key, guarding against a
+// regression back to the bug where encrypt/decrypt silently used mismatched
+// key material instead of failing loudly.
+func TestDNSCryptEncryptDecryptWrongKeyFails(t *testing.T) {
+	var serverSK, otherSK [32]byte
+	rand.Read(serverSK[:])
+	rand.Read(otherSK[:])
+	serverPK, _ := curve25519.X25519(serverSK[:], curve25519.Basepoint)
+
+	r := &DNSCryptResolver{}
+	copy(r.serverPK[:], serverPK)
+
+	_, clientSK, nonce, err := r.encrypt([]byte("query"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	// Seal a "response" under the wrong server key entirely.
+	wrongShared, _ := curve25519.X25519(otherSK[:], serverPK)
+	aead, _ := chacha20poly1305.NewX(wrongShared)
+	ciphertext := aead.Seal(nil, nonce, []byte("response"), nil)
+	wire := append(make([]byte, len(nonce)), ciphertext...)
+
+	if _, err := r.decrypt(wire, clientSK, nonce); err == nil {
+		t.Fatalf("decrypt unexpectedly succeeded with mismatched key material")
+	}
+}
+
+// buildCertificate assembles a signed DNSCrypt certificate TXT payload for
+// resolverPK, signed by providerSK, valid for the given time window.
+func buildCertificate(providerSK ed25519.PrivateKey, resolverPK [32]byte, serial uint32, start, end time.Time) []byte {
+	signed := make([]byte, certFixedSignedLen)
+	copy(signed[:32], resolverPK[:])
+	// client-magic (signed[32:40]) is left zeroed; it isn't checked here.
+	binary.BigEndian.PutUint32(signed[40:44], serial)
+	binary.BigEndian.PutUint32(signed[44:48], uint32(start.Unix()))
+	binary.BigEndian.PutUint32(signed[48:52], uint32(end.Unix()))
+
+	signature := ed25519.Sign(providerSK, signed)
+
+	cert := make([]byte, 0, certMinSize)
+	cert = append(cert, certMagic[:]...)
+	cert = append(cert, 0, esVersionXChaCha20Poly1305) // es-version
+	cert = append(cert, 0, 0)                          // protocol minor version
+	cert = append(cert, signature...)
+	cert = append(cert, signed...)
+	return cert
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	providerPK, providerSK, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate provider key: %v", err)
+	}
+
+	var resolverPK [32]byte
+	rand.Read(resolverPK[:])
+
+	now := time.Now()
+	cert := buildCertificate(providerSK, resolverPK, 1, now.Add(-time.Hour), now.Add(time.Hour))
+
+	pk, err := verifyCertificate([][]byte{cert}, providerPK)
+	if err != nil {
+		t.Fatalf("verifyCertificate failed on a validly signed certificate: %v", err)
+	}
+	if pk != resolverPK {
+		t.Fatalf("verifyCertificate returned %x, want %x", pk, resolverPK)
+	}
+
+	// Tampering with a single byte of the signed portion must invalidate
+	// the signature.
+	tampered := append([]byte{}, cert...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := verifyCertificate([][]byte{tampered}, providerPK); err == nil {
+		t.Fatalf("verifyCertificate accepted a tampered certificate")
+	}
+
+	// A certificate outside its validity window must be rejected.
+	expired := buildCertificate(providerSK, resolverPK, 1, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if _, err := verifyCertificate([][]byte{expired}, providerPK); err == nil {
+		t.Fatalf("verifyCertificate accepted an expired certificate")
+	}
+
+	// The newest (highest serial) valid certificate must win when more than
+	// one is offered.
+	var newerPK [32]byte
+	rand.Read(newerPK[:])
+	newer := buildCertificate(providerSK, newerPK, 2, now.Add(-time.Hour), now.Add(time.Hour))
+
+	pk, err = verifyCertificate([][]byte{cert, newer}, providerPK)
+	if err != nil {
+		t.Fatalf("verifyCertificate failed with multiple valid certificates: %v", err)
+	}
+	if pk != newerPK {
+		t.Fatalf("verifyCertificate picked serial 1 over the newer serial 2 certificate")
+	}
+}
+
+// TestRawTXTAnswersSurvivesWirePresentationRoundTrip confirms rawTXTAnswers
+// recovers a certificate's exact bytes after a real wire pack/unpack, unlike
+// the generic extractAnswers/dataFromRR path which renders RRs through their
+// quoted, \DDD-escaped presentation form. The certificate here deliberately
+// spans more than one 255-byte TXT character-string and contains a NUL, a
+// backslash, and a double quote, all of which the presentation form escapes.
+func TestRawTXTAnswersSurvivesWirePresentationRoundTrip(t *testing.T) {
+	providerPK, providerSK, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate provider key: %v", err)
+	}
+
+	var resolverPK [32]byte
+	rand.Read(resolverPK[:])
+
+	now := time.Now()
+	cert := buildCertificate(providerSK, resolverPK, 1, now.Add(-time.Hour), now.Add(time.Hour))
+	// Pad past a single character-string and plant bytes the presentation
+	// form would mangle.
+	cert = append(cert, bytes.Repeat([]byte{0x00}, 300)...)
+	cert = append(cert, []byte(`\"`)...)
+	// The padding sits where certificate extensions go, inside the portion
+	// the signature covers, so the certificate is re-signed over it.
+	copy(cert[certHeaderSize:certSignedOffset], ed25519.Sign(providerSK, cert[certSignedOffset:]))
+
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: "2.dnscrypt-cert.example.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+		Txt: txtCharacterStrings(cert),
+	}
+	msg := new(dns.Msg)
+	msg.Answer = append(msg.Answer, rr)
+
+	wire, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack the TXT response: %v", err)
+	}
+
+	var resp dns.Msg
+	if err := resp.Unpack(wire); err != nil {
+		t.Fatalf("failed to unpack the TXT response: %v", err)
+	}
+
+	certs := rawTXTAnswers(&resp)
+	if len(certs) != 1 {
+		t.Fatalf("rawTXTAnswers returned %d certificates, want 1", len(certs))
+	}
+	if !bytes.Equal(certs[0], cert) {
+		t.Fatalf("rawTXTAnswers did not round-trip the certificate bytes unchanged")
+	}
+
+	pk, err := verifyCertificate(certs, providerPK)
+	if err != nil {
+		t.Fatalf("verifyCertificate failed on a wire-round-tripped certificate: %v", err)
+	}
+	if pk != resolverPK {
+		t.Fatalf("verifyCertificate returned %x, want %x", pk, resolverPK)
+	}
+}
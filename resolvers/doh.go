@@ -0,0 +1,123 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+// dohMediaType is the wire-format media type required by RFC 8484.
+const dohMediaType = "application/dns-message"
+
+// DoHResolver queries a resolver over DNS-over-HTTPS (RFC 8484) using the POST
+// wire-format, with an http.Client configured to keep HTTP/2 connections warm
+// so repeated queries don't pay a new TLS handshake.
+type DoHResolver struct {
+	sync.Mutex
+
+	endpoint string
+	client   *http.Client
+	cookies  *cookieCache
+	stopped  bool
+}
+
+// NewDoHResolver initializes a DoHResolver for the given https:// endpoint.
+func NewDoHResolver(endpoint string) (*DoHResolver, error) {
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid DoH endpoint %s: %v", endpoint, err)
+	}
+
+	return &DoHResolver{
+		endpoint: endpoint,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		cookies: newCookieCache(),
+	}, nil
+}
+
+// Address implements the Resolver interface.
+func (r *DoHResolver) Address() string {
+	return r.endpoint
+}
+
+// Stop implements the Resolver interface.
+func (r *DoHResolver) Stop() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+// Stopped implements the Resolver interface.
+func (r *DoHResolver) Stopped() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stopped
+}
+
+// Resolve implements the Resolver interface.
+func (r *DoHResolver) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	if r.Stopped() {
+		return nil, false, nil, ErrResolverStopped
+	}
+
+	t, ok := dns.StringToType[qtype]
+	if !ok {
+		return nil, false, nil, fmt.Errorf("unsupported query type: %s", qtype)
+	}
+
+	wire, err := queryMessage(name, t, opts, r.endpoint, r.cookies).Pack()
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(wire))
+	if err != nil {
+		return nil, false, nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	httpResp, err := r.client.Do(req)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, false, nil, fmt.Errorf("%s: DoH server returned %s", r.endpoint, httpResp.Status)
+	}
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(body); err != nil {
+		return nil, false, nil, err
+	}
+	recordServerCookie(resp, r.endpoint, r.cookies)
+
+	return extractAnswers(resp), resp.Rcode == dns.RcodeNameError, extractEDNSInfo(resp), nil
+}
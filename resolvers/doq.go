@@ -0,0 +1,139 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/lucas-clemente/quic-go"
+	"github.com/miekg/dns"
+)
+
+// doqALPN is the ALPN token that identifies DNS-over-QUIC, per RFC 9250.
+var doqALPN = []string{"doq"}
+
+// DoQResolver queries a resolver over DNS-over-QUIC (RFC 9250), opening one
+// bidirectional QUIC stream per query on a shared QUIC session.
+type DoQResolver struct {
+	sync.Mutex
+
+	addr    string
+	sess    quic.Connection
+	cookies *cookieCache
+	stopped bool
+}
+
+// NewDoQResolver initializes a DoQResolver for addr (host:port). A bare IP
+// literal gets port 853; a hostname without a port is used as given.
+func NewDoQResolver(addr string) (*DoQResolver, error) {
+	return &DoQResolver{addr: addDefaultPort(addr, "853"), cookies: newCookieCache()}, nil
+}
+
+// Address implements the Resolver interface.
+func (r *DoQResolver) Address() string {
+	return r.addr
+}
+
+// Stop implements the Resolver interface.
+func (r *DoQResolver) Stop() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+	if r.sess != nil {
+		r.sess.CloseWithError(0, "")
+		r.sess = nil
+	}
+	return nil
+}
+
+// Stopped implements the Resolver interface.
+func (r *DoQResolver) Stopped() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stopped
+}
+
+// session returns the shared QUIC connection, establishing it on first use.
+func (r *DoQResolver) session(ctx context.Context) (quic.Connection, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.sess != nil {
+		return r.sess, nil
+	}
+
+	sess, err := quic.DialAddrContext(ctx, r.addr, &tls.Config{NextProtos: doqALPN}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r.sess = sess
+	return sess, nil
+}
+
+// Resolve implements the Resolver interface.
+func (r *DoQResolver) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	if r.Stopped() {
+		return nil, false, nil, ErrResolverStopped
+	}
+
+	t, ok := dns.StringToType[qtype]
+	if !ok {
+		return nil, false, nil, fmt.Errorf("unsupported query type: %s", qtype)
+	}
+
+	sess, err := r.session(ctx)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	stream, err := sess.OpenStreamSync(ctx)
+	if err != nil {
+		// The session may have gone stale; drop it so the next query reconnects.
+		r.Lock()
+		r.sess = nil
+		r.Unlock()
+		return nil, false, nil, err
+	}
+	msg := queryMessage(name, t, opts, r.addr, r.cookies)
+	// RFC 9250 requires the message ID be zero on the wire for DoQ.
+	msg.Id = 0
+
+	wire, err := msg.Pack()
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if _, err := stream.Write(wire); err != nil {
+		return nil, false, nil, err
+	}
+	// Half-close the write side so the server sees the query as complete.
+	stream.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	stream.SetReadDeadline(deadline)
+
+	body, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(body); err != nil {
+		return nil, false, nil, err
+	}
+	recordServerCookie(resp, r.addr, r.cookies)
+
+	return extractAnswers(resp), resp.Rcode == dns.RcodeNameError, extractEDNSInfo(resp), nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+// DoTResolver queries a resolver over DNS-over-TLS (RFC 7858), reusing a single
+// TLS connection across queries rather than reconnecting for every lookup.
+type DoTResolver struct {
+	sync.Mutex
+
+	addr    string
+	sni     string
+	conn    *dns.Conn
+	cookies *cookieCache
+	stopped bool
+
+	// exchangeMu serializes the write/read pair of every query issued over
+	// conn. The pool drives Resolve from many concurrent goroutines, and a
+	// shared stream connection has no framing that lets two in-flight
+	// exchanges tell their own response apart from each other's; without
+	// this, concurrent callers can read back one another's answers.
+	exchangeMu sync.Mutex
+}
+
+// NewDoTResolver initializes a DoTResolver for addr, which is a host:port pair
+// (port defaults to 853 when omitted). The host is also used as the TLS SNI value.
+func NewDoTResolver(addr string) (*DoTResolver, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+		addr = net.JoinHostPort(addr, "853")
+	}
+
+	return &DoTResolver{
+		addr:    addr,
+		sni:     host,
+		cookies: newCookieCache(),
+	}, nil
+}
+
+// Address implements the Resolver interface.
+func (r *DoTResolver) Address() string {
+	return r.addr
+}
+
+// Stop implements the Resolver interface.
+func (r *DoTResolver) Stop() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	return nil
+}
+
+// Stopped implements the Resolver interface.
+func (r *DoTResolver) Stopped() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stopped
+}
+
+// connection returns the live TLS connection, dialing (or redialing) as needed.
+func (r *DoTResolver) connection() (*dns.Conn, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.conn != nil {
+		return r.conn, nil
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	tlsConn, err := tls.DialWithDialer(dialer, "tcp", r.addr, &tls.Config{ServerName: r.sni})
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn = &dns.Conn{Conn: tlsConn}
+	return r.conn, nil
+}
+
+// Resolve implements the Resolver interface.
+func (r *DoTResolver) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	if r.Stopped() {
+		return nil, false, nil, ErrResolverStopped
+	}
+
+	t, ok := dns.StringToType[qtype]
+	if !ok {
+		return nil, false, nil, fmt.Errorf("unsupported query type: %s", qtype)
+	}
+
+	conn, err := r.connection()
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	client := &dns.Client{Net: "tcp-tls", Timeout: 5 * time.Second}
+
+	// The connection is shared across every concurrent caller, and a stream
+	// transport has nothing that lets one exchange's response be told apart
+	// from another's, so the write/read pair must run without interleaving
+	// from a second goroutine's exchange on the same conn.
+	r.exchangeMu.Lock()
+	resp, _, err := client.ExchangeWithConnContext(ctx, queryMessage(name, t, opts, r.addr, r.cookies), conn)
+	r.exchangeMu.Unlock()
+	if err != nil {
+		// The connection may have gone stale; drop it so the next query redials.
+		r.Lock()
+		if r.conn != nil {
+			r.conn.Close()
+			r.conn = nil
+		}
+		r.Unlock()
+
+		return nil, false, nil, err
+	}
+	recordServerCookie(resp, r.addr, r.cookies)
+
+	return extractAnswers(resp), resp.Rcode == dns.RcodeNameError, extractEDNSInfo(resp), nil
+}
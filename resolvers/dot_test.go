@@ -0,0 +1,86 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// TestDoTResolverConcurrentResolveDoesNotCrossDeliver drives many concurrent
+// Resolve calls over one shared connection and confirms every caller gets
+// back the answer for its own query. Run with -race: before the exchange
+// was serialized with exchangeMu, concurrent writers/readers on the single
+// *dns.Conn had no way to tell their own response apart from another
+// in-flight query's, and a caller could read back someone else's answer.
+func TestDoTResolverConcurrentResolveDoesNotCrossDeliver(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		sc := &dns.Conn{Conn: server}
+		for {
+			msg, err := sc.ReadMsg()
+			if err != nil {
+				return
+			}
+
+			q := msg.Question[0]
+			rr, err := dns.NewRR(fmt.Sprintf("%s 60 IN TXT \"%s\"", q.Name, RemoveLastDot(q.Name)))
+			if err != nil {
+				return
+			}
+
+			resp := new(dns.Msg)
+			resp.SetReply(msg)
+			resp.Answer = append(resp.Answer, rr)
+
+			if err := sc.WriteMsg(resp); err != nil {
+				return
+			}
+		}
+	}()
+
+	r := &DoTResolver{
+		addr:    "test.invalid:853",
+		sni:     "test.invalid",
+		cookies: newCookieCache(),
+		conn:    &dns.Conn{Conn: client},
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			name := fmt.Sprintf("query-%d.example.com", i)
+			answers, _, _, err := r.Resolve(context.Background(), name, "TXT", PriorityLow, nil)
+			if err != nil {
+				errs <- fmt.Errorf("query %d: %v", i, err)
+				return
+			}
+
+			want := "\"" + name + "\""
+			if len(answers) != 1 || answers[0].Data != want {
+				errs <- fmt.Errorf("query %d: got cross-delivered or missing answer: %+v", i, answers)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// defaultUDPBufSize mirrors the conservative default most resolvers advertise
+// when no explicit buffer size is requested.
+const defaultUDPBufSize = 1232
+
+// EDNSOptions captures the EDNS(0) behavior requested for a query: the DO bit
+// for DNSSEC, an EDNS Client Subnet option, the advertised UDP payload size,
+// RFC 7873 cookies, NSID (RFC 5001), and the EDNS Expire option (RFC 7314).
+type EDNSOptions struct {
+	DNSSEC  bool
+	Subnet  *net.IPNet
+	BufSize uint16
+	Cookie  bool
+	NSID    bool
+	Expire  bool
+}
+
+// cookieCache remembers the server cookie most recently returned by each
+// resolver address, per RFC 7873 section 5.3, so subsequent queries to that
+// server can be recognized without a fresh round trip.
+type cookieCache struct {
+	mu     sync.Mutex
+	client map[string][8]byte
+	server map[string][]byte
+}
+
+func newCookieCache() *cookieCache {
+	return &cookieCache{
+		client: make(map[string][8]byte),
+		server: make(map[string][]byte),
+	}
+}
+
+// clientCookie returns the 8-byte client cookie used for addr, generating and
+// caching a fresh random one the first time addr is seen.
+func (c *cookieCache) clientCookie(addr string) [8]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cc, found := c.client[addr]; found {
+		return cc
+	}
+
+	var cc [8]byte
+	rand.Read(cc[:])
+	c.client[addr] = cc
+	return cc
+}
+
+// serverCookie returns the cached server cookie for addr, if any.
+func (c *cookieCache) serverCookie(addr string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.server[addr]
+}
+
+// storeServerCookie remembers the server cookie returned by addr.
+func (c *cookieCache) storeServerCookie(addr string, sc []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.server[addr] = sc
+}
+
+// attachEDNS adds the OPT pseudo-record requested by opts to msg. addr and
+// cookies are only needed when opts.Cookie is set, so callers that never
+// enable cookies can pass a nil cache.
+func attachEDNS(msg *dns.Msg, opts *EDNSOptions, addr string, cookies *cookieCache) {
+	if opts == nil {
+		return
+	}
+
+	bufsize := opts.BufSize
+	if bufsize == 0 {
+		bufsize = defaultUDPBufSize
+	}
+
+	msg.SetEdns0(bufsize, opts.DNSSEC)
+	o := msg.IsEdns0()
+	if o == nil {
+		return
+	}
+
+	if opts.Subnet != nil {
+		o.Option = append(o.Option, subnetOption(opts.Subnet))
+	}
+	if opts.NSID {
+		o.Option = append(o.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+	}
+	if opts.Expire {
+		o.Option = append(o.Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE})
+	}
+	if opts.Cookie && cookies != nil {
+		cc := cookies.clientCookie(addr)
+		cookie := &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE}
+		cookie.Cookie = encodeCookie(cc, cookies.serverCookie(addr))
+		o.Option = append(o.Option, cookie)
+	}
+}
+
+// encodeCookie hex-encodes the client cookie, appending the cached server
+// cookie when one is available, matching the wire format miekg/dns expects
+// for the EDNS0_COOKIE option's Cookie field.
+func encodeCookie(client [8]byte, server []byte) string {
+	const hextable = "0123456789abcdef"
+
+	buf := make([]byte, 0, (8+len(server))*2)
+	for _, b := range append(client[:], server...) {
+		buf = append(buf, hextable[b>>4], hextable[b&0x0f])
+	}
+	return string(buf)
+}
+
+// subnetOption builds the EDNS Client Subnet option (RFC 7871) for network.
+func subnetOption(network *net.IPNet) *dns.EDNS0_SUBNET {
+	e := &dns.EDNS0_SUBNET{
+		Code:    dns.EDNS0SUBNET,
+		Address: network.IP,
+	}
+
+	ones, bits := network.Mask.Size()
+	e.SourceNetmask = uint8(ones)
+
+	if network.IP.To4() != nil {
+		e.Family = 1
+	} else {
+		e.Family = 2
+	}
+	if bits == 0 {
+		e.Family = 0
+	}
+
+	return e
+}
+
+// EDNSInfo surfaces the parts of a response that the plain answer slice
+// doesn't carry: the AA/TC/AD/CD header bits, the response code, the NSID
+// the server identified itself with (when requested), and the SOA MINIMUM
+// field from the authority section, used as the RFC 2308 negative-caching
+// TTL when the response carried no answers.
+type EDNSInfo struct {
+	AA     bool
+	TC     bool
+	AD     bool
+	CD     bool
+	Rcode  int
+	NSID   string
+	NegTTL uint32
+
+	// ResolverAddr and Latency are filled in by the ResolverPool, not the
+	// individual Resolver, so callers can tell which transport answered a
+	// query and how long it took without threading extra return values
+	// through every Resolver implementation.
+	ResolverAddr string
+	Latency      time.Duration
+}
+
+// extractEDNSInfo pulls the AA/TC/AD/CD flags, the response code, any NSID
+// option, and the negative-caching TTL (the authority section's SOA MINIMUM,
+// per RFC 2308) out of resp.
+func extractEDNSInfo(resp *dns.Msg) *EDNSInfo {
+	if resp == nil {
+		return nil
+	}
+
+	info := &EDNSInfo{
+		AA:    resp.Authoritative,
+		TC:    resp.Truncated,
+		AD:    resp.AuthenticatedData,
+		CD:    resp.CheckingDisabled,
+		Rcode: resp.Rcode,
+	}
+
+	if o := resp.IsEdns0(); o != nil {
+		for _, opt := range o.Option {
+			if n, ok := opt.(*dns.EDNS0_NSID); ok {
+				info.NSID = n.String()
+			}
+		}
+	}
+
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			info.NegTTL = soa.Minttl
+			break
+		}
+	}
+
+	return info
+}
+
+// recordServerCookie inspects resp for a returned EDNS0_COOKIE option and
+// caches the server portion for addr so future queries can present it back.
+func recordServerCookie(resp *dns.Msg, addr string, cookies *cookieCache) {
+	if resp == nil || cookies == nil {
+		return
+	}
+
+	o := resp.IsEdns0()
+	if o == nil {
+		return
+	}
+
+	for _, opt := range o.Option {
+		if c, ok := opt.(*dns.EDNS0_COOKIE); ok && len(c.Cookie) > 16 {
+			// c.Cookie is hex text; encodeCookie expects the raw bytes.
+			if sc, err := hex.DecodeString(c.Cookie[16:]); err == nil {
+				cookies.storeServerCookie(addr, sc)
+			}
+		}
+	}
+}
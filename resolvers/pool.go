@@ -0,0 +1,269 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// ResolverPool distributes queries across a set of Resolvers, each of which may
+// speak a different transport (plain UDP/TCP or one of the encrypted-DNS
+// protocols), and tracks per-transport latency/error metrics independently.
+type ResolverPool struct {
+	sync.Mutex
+
+	resolvers []Resolver
+	rate      *rateMonitor
+	next      int
+	wildcards map[string]WildcardType
+	overrides map[string]*EDNSOptions
+	strategy  QueryStrategy
+	cache     *AnswerCache
+}
+
+// NewResolverPool builds a ResolverPool from the resolver specs in cfg.Resolvers.
+// Each spec is parsed by NewResolver, so bare IP addresses keep behaving as plain
+// UDP resolvers while URIs such as https://, tls://, quic://, and sdns:// select
+// the matching encrypted-DNS transport.
+func NewResolverPool(cfg *config.Config) (*ResolverPool, error) {
+	pool := &ResolverPool{
+		rate:      newRateMonitor(),
+		wildcards: make(map[string]WildcardType),
+	}
+
+	for _, spec := range cfg.Resolvers {
+		res, err := NewResolver(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize resolver %s: %v", spec, err)
+		}
+		pool.resolvers = append(pool.resolvers, res)
+	}
+
+	if len(pool.resolvers) == 0 {
+		return nil, fmt.Errorf("no resolvers were provided to the pool")
+	}
+	return pool, nil
+}
+
+// Stop releases every Resolver held by the pool.
+func (rp *ResolverPool) Stop() error {
+	rp.Lock()
+	defer rp.Unlock()
+
+	for _, res := range rp.resolvers {
+		res.Stop()
+	}
+	return nil
+}
+
+// nextResolver returns the next non-stopped Resolver, round-robin.
+func (rp *ResolverPool) nextResolver() Resolver {
+	rp.Lock()
+	defer rp.Unlock()
+
+	for i := 0; i < len(rp.resolvers); i++ {
+		idx := rp.next % len(rp.resolvers)
+		rp.next++
+
+		if res := rp.resolvers[idx]; !res.Stopped() {
+			return res
+		}
+	}
+	return nil
+}
+
+// Resolve sends name/qtype to the next available Resolver in the pool,
+// recording the outcome in the rate monitor under that resolver's own
+// transport, so a DoH endpoint's metrics are kept apart from those of UDP
+// lookups to the same server. opts carries the EDNS(0) behavior requested
+// for the query; it may be nil, and is merged with any per-resolver
+// override registered for the chosen resolver's address.
+func (rp *ResolverPool) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	if rp.excludedByStrategy(qtype) {
+		return nil, false, nil, nil
+	}
+
+	if cache := rp.getCache(); cache != nil {
+		if answers, nxdomain, found := cache.Get(name, qtype); found {
+			return answers, nxdomain, nil, nil
+		}
+	}
+
+	res := rp.nextResolver()
+	if res == nil {
+		return nil, false, nil, fmt.Errorf("the resolver pool has no resolvers available")
+	}
+
+	start := time.Now()
+	answers, nxdomain, info, err := res.Resolve(ctx, name, qtype, priority, rp.ednsOptions(res.Address(), opts))
+	latency := time.Since(start)
+	rp.rate.Report(res, latency, err != nil)
+
+	if err == nil {
+		if info != nil {
+			info.ResolverAddr = res.Address()
+			info.Latency = latency
+		}
+		if cache := rp.getCache(); cache != nil {
+			var negTTL uint32
+			if info != nil {
+				negTTL = info.NegTTL
+			}
+			cache.Store(name, qtype, answers, nxdomain, negTTL)
+		}
+	}
+
+	return answers, nxdomain, info, err
+}
+
+// SetCache installs cache as the pool's answer cache, consulted by Resolve
+// before any resolver is queried. Passing nil disables caching.
+func (rp *ResolverPool) SetCache(cache *AnswerCache) {
+	rp.Lock()
+	defer rp.Unlock()
+
+	rp.cache = cache
+}
+
+// getCache returns the pool's current answer cache, if any.
+func (rp *ResolverPool) getCache() *AnswerCache {
+	rp.Lock()
+	defer rp.Unlock()
+
+	return rp.cache
+}
+
+// SetResolverOverride pins the EDNS Client Subnet and cookie behavior used
+// for every query sent to the resolver at addr, regardless of what
+// individual callers request, letting configuration single out a resolver
+// that requires a particular subnet or cookie policy.
+func (rp *ResolverPool) SetResolverOverride(addr string, opts *EDNSOptions) {
+	rp.Lock()
+	defer rp.Unlock()
+
+	if rp.overrides == nil {
+		rp.overrides = make(map[string]*EDNSOptions)
+	}
+	rp.overrides[addr] = opts
+}
+
+// ednsOptions applies any override registered for addr on top of opts,
+// limited to the Subnet and Cookie fields the override is permitted to pin.
+func (rp *ResolverPool) ednsOptions(addr string, opts *EDNSOptions) *EDNSOptions {
+	rp.Lock()
+	override, found := rp.overrides[addr]
+	rp.Unlock()
+
+	if !found {
+		return opts
+	}
+
+	merged := EDNSOptions{}
+	if opts != nil {
+		merged = *opts
+	}
+	if override.Subnet != nil {
+		merged.Subnet = override.Subnet
+	}
+	if override.Cookie {
+		merged.Cookie = true
+	}
+	return &merged
+}
+
+// SetQueryStrategy pins the address family the pool queries for, restricting
+// Resolve to a single family (UseIPv4/UseIPv6) or letting both through
+// (UseIP, PreferIPv4, PreferIPv6); Resolve applies no family preference.
+func (rp *ResolverPool) SetQueryStrategy(qs QueryStrategy) {
+	rp.Lock()
+	defer rp.Unlock()
+
+	rp.strategy = qs
+}
+
+// excludedByStrategy reports whether qtype must never be sent on the wire
+// under the pool's current query strategy.
+func (rp *ResolverPool) excludedByStrategy(qtype string) bool {
+	rp.Lock()
+	qs := rp.strategy
+	rp.Unlock()
+
+	switch qs {
+	case UseIPv4:
+		return qtype == "AAAA"
+	case UseIPv6:
+		return qtype == "A"
+	}
+	return false
+}
+
+// SubdomainToDomain returns the root domain name within name that matches one
+// of the scope's known domains, preserving the prior single-transport behavior.
+func (rp *ResolverPool) SubdomainToDomain(name string) string {
+	name = strings.ToLower(RemoveLastDot(name))
+
+	var domain string
+	for idx := len(name); idx > 0; idx = strings.LastIndex(name[:idx], ".") {
+		sub := name[:idx]
+		if _, _, _, err := rp.Resolve(context.Background(), sub, "NS", PriorityLow, nil); err == nil {
+			domain = sub
+		}
+	}
+	return domain
+}
+
+// GetWildcardType reports whether req.Name falls under a wildcard subdomain,
+// caching the verdict per domain so repeated names don't repeat the probe.
+func (rp *ResolverPool) GetWildcardType(ctx context.Context, req *requests.DNSRequest) WildcardType {
+	rp.Lock()
+	if wt, found := rp.wildcards[req.Domain]; found {
+		rp.Unlock()
+		return wt
+	}
+	rp.Unlock()
+
+	probeType := "A"
+	if rp.excludedByStrategy(probeType) {
+		probeType = "AAAA"
+	}
+
+	wt := WildcardTypeNone
+	if answers, _, _, err := rp.Resolve(ctx, "_dnsamass_wildcard_check."+req.Domain, probeType, PriorityLow, nil); err == nil && len(answers) > 0 {
+		wt = WildcardTypeStatic
+	}
+
+	rp.Lock()
+	rp.wildcards[req.Domain] = wt
+	rp.Unlock()
+
+	return wt
+}
+
+// MatchesWildcard reports whether req.Domain answered the wildcard probe.
+// req.Records are not compared against the probe's answers, so every name
+// under such a domain matches.
+func (rp *ResolverPool) MatchesWildcard(ctx context.Context, req *requests.DNSRequest) bool {
+	return rp.GetWildcardType(ctx, req) != WildcardTypeNone
+}
+
+// ResolverStats summarizes the query/error counts the rate monitor has
+// accumulated for one resolver address, across every transport queried there.
+type ResolverStats struct {
+	Queries int
+	Errors  int
+}
+
+// Stats returns a snapshot of the pool's per-resolver query/error counts,
+// keyed by resolver address, for reporting by callers such as the dns
+// subcommand's JSON summary.
+func (rp *ResolverPool) Stats() map[string]ResolverStats {
+	return rp.rate.snapshot()
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// stubResolver is a Resolver that records whether it was queried, for
+// asserting the pool did or didn't reach the wire.
+type stubResolver struct {
+	addr     string
+	queried  []string
+	answers  []requests.DNSAnswer
+	nxdomain bool
+}
+
+func (s *stubResolver) Address() string { return s.addr }
+func (s *stubResolver) Stop() error     { return nil }
+func (s *stubResolver) Stopped() bool   { return false }
+
+func (s *stubResolver) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	s.queried = append(s.queried, qtype)
+	return s.answers, s.nxdomain, nil, nil
+}
+
+// TestResolverPoolExcludedByStrategy confirms UseIPv4/UseIPv6 keep the pool
+// from ever sending the excluded query type on the wire, while the default
+// strategy leaves both families through.
+func TestResolverPoolExcludedByStrategy(t *testing.T) {
+	stub := &stubResolver{addr: "127.0.0.1:53", answers: []requests.DNSAnswer{{TTL: 60}}}
+	rp := &ResolverPool{rate: newRateMonitor(), resolvers: []Resolver{stub}}
+
+	if _, _, _, err := rp.Resolve(context.Background(), "www.example.com", "A", PriorityLow, nil); err != nil {
+		t.Fatalf("Resolve with the default strategy failed: %v", err)
+	}
+	if _, _, _, err := rp.Resolve(context.Background(), "www.example.com", "AAAA", PriorityLow, nil); err != nil {
+		t.Fatalf("Resolve with the default strategy failed: %v", err)
+	}
+	if len(stub.queried) != 2 {
+		t.Fatalf("default strategy queried %v, want both A and AAAA sent", stub.queried)
+	}
+
+	rp.SetQueryStrategy(UseIPv4)
+	stub.queried = nil
+	if _, _, _, err := rp.Resolve(context.Background(), "www.example.com", "AAAA", PriorityLow, nil); err != nil {
+		t.Fatalf("Resolve returned an error instead of an empty exclusion result: %v", err)
+	}
+	if len(stub.queried) != 0 {
+		t.Fatalf("UseIPv4 strategy let AAAA reach the resolver: %v", stub.queried)
+	}
+	if _, _, _, err := rp.Resolve(context.Background(), "www.example.com", "A", PriorityLow, nil); err != nil {
+		t.Fatalf("Resolve with UseIPv4 failed: %v", err)
+	}
+	if len(stub.queried) != 1 || stub.queried[0] != "A" {
+		t.Fatalf("UseIPv4 strategy did not let A through: %v", stub.queried)
+	}
+
+	rp.SetQueryStrategy(UseIPv6)
+	stub.queried = nil
+	if _, _, _, err := rp.Resolve(context.Background(), "www.example.com", "A", PriorityLow, nil); err != nil {
+		t.Fatalf("Resolve returned an error instead of an empty exclusion result: %v", err)
+	}
+	if len(stub.queried) != 0 {
+		t.Fatalf("UseIPv6 strategy let A reach the resolver: %v", stub.queried)
+	}
+	if _, _, _, err := rp.Resolve(context.Background(), "www.example.com", "AAAA", PriorityLow, nil); err != nil {
+		t.Fatalf("Resolve with UseIPv6 failed: %v", err)
+	}
+	if len(stub.queried) != 1 || stub.queried[0] != "AAAA" {
+		t.Fatalf("UseIPv6 strategy did not let AAAA through: %v", stub.queried)
+	}
+}
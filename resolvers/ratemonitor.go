@@ -0,0 +1,124 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// transportStats tracks the rolling latency/error metrics for one transport,
+// kept apart from those of the other transports sharing its IP. The metrics
+// are recorded and reported; no query is delayed or rerouted on their account.
+type transportStats struct {
+	sync.Mutex
+
+	queries  int
+	errors   int
+	totalDur time.Duration
+}
+
+func (s *transportStats) record(dur time.Duration, failed bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.queries++
+	s.totalDur += dur
+	if failed {
+		s.errors++
+	}
+}
+
+func (s *transportStats) snapshot() (avgLatency time.Duration, errorRate float64) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.queries == 0 {
+		return 0, 0
+	}
+	return s.totalDur / time.Duration(s.queries), float64(s.errors) / float64(s.queries)
+}
+
+// rateMonitor keeps independent metrics per transport kind, since a DoH
+// resolver and a UDP resolver on the same server address fail and recover on
+// very different timescales and shouldn't share a single error budget.
+type rateMonitor struct {
+	mu    sync.Mutex
+	stats map[string]*transportStats
+}
+
+func newRateMonitor() *rateMonitor {
+	return &rateMonitor{stats: make(map[string]*transportStats)}
+}
+
+// key identifies a transport's metrics bucket as "<addr>|<transport kind>".
+func (m *rateMonitor) key(addr string, kind string) string {
+	return addr + "|" + kind
+}
+
+func (m *rateMonitor) statsFor(addr, kind string) *transportStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.key(addr, kind)
+	s, found := m.stats[k]
+	if !found {
+		s = new(transportStats)
+		m.stats[k] = s
+	}
+	return s
+}
+
+// Report records the outcome of a single query issued over res.
+func (m *rateMonitor) Report(res Resolver, dur time.Duration, failed bool) {
+	m.statsFor(res.Address(), transportKind(res)).record(dur, failed)
+}
+
+// snapshot aggregates the query/error counts recorded for every transport
+// kind seen at each resolver address, so a resolver queried over more than
+// one transport still reports one overall success rate.
+func (m *rateMonitor) snapshot() map[string]ResolverStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agg := make(map[string]ResolverStats, len(m.stats))
+	for k, s := range m.stats {
+		addr := k
+		if i := strings.LastIndex(k, "|"); i >= 0 {
+			addr = k[:i]
+		}
+
+		s.Lock()
+		queries, errors := s.queries, s.errors
+		s.Unlock()
+
+		stat := agg[addr]
+		stat.Queries += queries
+		stat.Errors += errors
+		agg[addr] = stat
+	}
+	return agg
+}
+
+// transportKind names the Go type behind the Resolver interface, so metrics
+// never mix transports that merely happen to share a server address.
+func transportKind(res Resolver) string {
+	switch res.(type) {
+	case *UDPResolver:
+		return "udp"
+	case *TCPResolver:
+		return "tcp"
+	case *DoTResolver:
+		return "dot"
+	case *DoHResolver:
+		return "doh"
+	case *DoQResolver:
+		return "doq"
+	case *DNSCryptResolver:
+		return "dnscrypt"
+	default:
+		return "unknown"
+	}
+}
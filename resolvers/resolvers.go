@@ -0,0 +1,94 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// QueryPriority is used to express how quickly a Resolver should service a request.
+type QueryPriority int
+
+// Query priorities supported by the Resolver implementations.
+const (
+	PriorityLow QueryPriority = iota
+	PriorityHigh
+)
+
+// WildcardType identifies the type of wildcard matching detected on a subdomain.
+type WildcardType int
+
+// Wildcard types returned by ResolverPool.GetWildcardType.
+const (
+	WildcardTypeNone WildcardType = iota
+	WildcardTypeStatic
+	WildcardTypeDynamic
+)
+
+// QueryStrategy controls which address families the ResolverPool queries and
+// returns answers for, mirroring the dual-stack options common DNS-capable
+// routers expose.
+type QueryStrategy int
+
+// Query strategies supported by the ResolverPool.
+const (
+	// UseIP queries both A and AAAA without restriction.
+	UseIP QueryStrategy = iota
+	// UseIPv4 queries only A records; AAAA is never sent on the wire.
+	UseIPv4
+	// UseIPv6 queries only AAAA records; A is never sent on the wire.
+	UseIPv6
+	// PreferIPv4 lets both A and AAAA queries through. The pool answers
+	// each query type on its own and does not filter by family; the dns
+	// subcommand drops the AAAA answers for a name when A answers were found.
+	PreferIPv4
+	// PreferIPv6 lets both A and AAAA queries through. The pool answers
+	// each query type on its own and does not filter by family; the dns
+	// subcommand drops the A answers for a name when AAAA answers were found.
+	PreferIPv6
+)
+
+// ErrResolverStopped is returned when a request is made of a Resolver that has already stopped.
+var ErrResolverStopped = errors.New("the resolver has stopped servicing requests")
+
+// Resolver abstracts the transport a query is sent over so the ResolverPool can treat
+// plain UDP/TCP resolvers and the encrypted-DNS transports identically.
+type Resolver interface {
+	// Address returns the endpoint the Resolver was constructed to query.
+	Address() string
+
+	// Resolve performs the query and returns the answers obtained, whether the
+	// response indicated the name does not exist, the OPT/header details of
+	// the response (returned for every response, whether or not opts
+	// requested EDNS), and any error encountered. A nil opts performs a query
+	// with no EDNS(0) record at all.
+	Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error)
+
+	// Stop releases the resources held by the Resolver. It is safe to call more than once.
+	Stop() error
+
+	// Stopped indicates whether Stop has already been called.
+	Stopped() bool
+}
+
+// RemoveLastDot trims a single trailing dot from a fully-qualified domain name.
+func RemoveLastDot(name string) string {
+	sz := len(name)
+	if sz > 0 && name[sz-1] == '.' {
+		return name[:sz-1]
+	}
+	return name
+}
+
+// fqdn ensures the name ends with a single trailing dot, as required by the DNS wire format.
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
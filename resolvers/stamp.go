@@ -0,0 +1,79 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// dnsCryptStamp holds the fields decoded from an `sdns://` DNSCrypt stamp.
+// See https://dnscrypt.info/stamps-specifications for the wire format.
+type dnsCryptStamp struct {
+	Addr         string
+	ProviderPK   []byte
+	ProviderName string
+}
+
+// parseDNSCryptStamp decodes the base64url payload of an sdns:// stamp for the
+// DNSCrypt protocol (type 0x01). Anonymized-relay and DoH stamps are not supported.
+func parseDNSCryptStamp(stamp string) (*dnsCryptStamp, error) {
+	raw := strings.TrimPrefix(stamp, "sdns://")
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sdns stamp encoding: %v", err)
+	}
+	if len(data) < 1 {
+		return nil, fmt.Errorf("empty sdns stamp")
+	}
+
+	const protoDNSCrypt = 0x01
+	if data[0] != protoDNSCrypt {
+		return nil, fmt.Errorf("unsupported sdns stamp protocol: 0x%02x", data[0])
+	}
+
+	p := data[1:]
+	// props (8 bytes), then a set of length-prefixed byte strings: server addr,
+	// provider public key, provider name.
+	if len(p) < 8 {
+		return nil, fmt.Errorf("truncated sdns stamp")
+	}
+	p = p[8:]
+
+	addr, p, err := readLP(p)
+	if err != nil {
+		return nil, err
+	}
+	pk, p, err := readLP(p)
+	if err != nil {
+		return nil, err
+	}
+	name, _, err := readLP(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dnsCryptStamp{
+		Addr:         addDefaultPort(string(addr), "53"),
+		ProviderPK:   pk,
+		ProviderName: string(name),
+	}, nil
+}
+
+// readLP consumes a single length-prefixed byte string from the front of p.
+func readLP(p []byte) (value, rest []byte, err error) {
+	if len(p) < 1 {
+		return nil, nil, fmt.Errorf("truncated sdns stamp field")
+	}
+
+	l := int(p[0])
+	p = p[1:]
+	if len(p) < l {
+		return nil, nil, fmt.Errorf("truncated sdns stamp field")
+	}
+
+	return p[:l], p[l:], nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+// UDPResolver queries a resolver over plain, unencrypted UDP.
+type UDPResolver struct {
+	sync.Mutex
+
+	addr    string
+	client  *dns.Client
+	cookies *cookieCache
+	stopped bool
+}
+
+// NewUDPResolver initializes a UDPResolver that sends queries to addr (host:port).
+func NewUDPResolver(addr string) (*UDPResolver, error) {
+	return &UDPResolver{
+		addr: addr,
+		client: &dns.Client{
+			Net:     "udp",
+			Timeout: 3 * time.Second,
+		},
+		cookies: newCookieCache(),
+	}, nil
+}
+
+// Address implements the Resolver interface.
+func (r *UDPResolver) Address() string {
+	return r.addr
+}
+
+// Stop implements the Resolver interface.
+func (r *UDPResolver) Stop() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stopped = true
+	return nil
+}
+
+// Stopped implements the Resolver interface.
+func (r *UDPResolver) Stopped() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stopped
+}
+
+// Resolve implements the Resolver interface.
+func (r *UDPResolver) Resolve(ctx context.Context, name, qtype string, priority QueryPriority, opts *EDNSOptions) ([]requests.DNSAnswer, bool, *EDNSInfo, error) {
+	if r.Stopped() {
+		return nil, false, nil, ErrResolverStopped
+	}
+
+	t, ok := dns.StringToType[qtype]
+	if !ok {
+		return nil, false, nil, fmt.Errorf("unsupported query type: %s", qtype)
+	}
+
+	msg := queryMessage(name, t, opts, r.addr, r.cookies)
+
+	resp, _, err := r.client.ExchangeContext(ctx, msg, r.addr)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	recordServerCookie(resp, r.addr, r.cookies)
+
+	return extractAnswers(resp), resp.Rcode == dns.RcodeNameError, extractEDNSInfo(resp), nil
+}
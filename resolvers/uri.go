@@ -0,0 +1,58 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NewResolver builds the Resolver implementation appropriate for spec, which may be a bare
+// IP address (UDP), or a URI identifying one of the encrypted-DNS transports:
+//
+//	https://host/path    -> DoH  (RFC 8484)
+//	tls://host:853        -> DoT  (RFC 7858)
+//	quic://host:853       -> DoQ  (RFC 9250)
+//	sdns://...            -> DNSCrypt
+//	tcp://host:53         -> plain TCP
+//
+// A bare IP or host:port with no scheme is treated as a plain UDP resolver, preserving the
+// behavior that existed before the additional transports were introduced.
+func NewResolver(spec string) (Resolver, error) {
+	switch {
+	case strings.HasPrefix(spec, "https://"):
+		return NewDoHResolver(spec)
+	case strings.HasPrefix(spec, "tls://"):
+		return NewDoTResolver(strings.TrimPrefix(spec, "tls://"))
+	case strings.HasPrefix(spec, "quic://"):
+		return NewDoQResolver(strings.TrimPrefix(spec, "quic://"))
+	case strings.HasPrefix(spec, "sdns://"):
+		return NewDNSCryptResolver(spec)
+	case strings.HasPrefix(spec, "tcp://"):
+		return NewTCPResolver(strings.TrimPrefix(spec, "tcp://"))
+	case strings.HasPrefix(spec, "udp://"):
+		return NewUDPResolver(addDefaultPort(strings.TrimPrefix(spec, "udp://"), "53"))
+	default:
+		return NewUDPResolver(addDefaultPort(spec, "53"))
+	}
+}
+
+// addDefaultPort appends port when spec is a bare address with no port of
+// its own.
+func addDefaultPort(spec, port string) string {
+	if _, _, err := net.SplitHostPort(spec); err == nil {
+		return spec
+	}
+	if ip := net.ParseIP(spec); ip != nil {
+		if strings.Contains(spec, ":") {
+			// A bare IPv6 literal needs brackets before the port is
+			// appended, or net.JoinHostPort-style parsing downstream
+			// can't tell the address's colons from the port separator.
+			return fmt.Sprintf("[%s]:%s", spec, port)
+		}
+		return fmt.Sprintf("%s:%s", spec, port)
+	}
+	return spec
+}
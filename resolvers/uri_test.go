@@ -0,0 +1,31 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package resolvers
+
+import "testing"
+
+// TestAddDefaultPort confirms a bare IPv6 literal gets bracketed before the
+// default port is appended, rather than producing an address with an
+// ambiguous mix of host and port colons.
+func TestAddDefaultPort(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"bare IPv4", "8.8.8.8", "8.8.8.8:53"},
+		{"IPv4 with port", "8.8.8.8:53", "8.8.8.8:53"},
+		{"bare IPv6", "2001:db8::1", "[2001:db8::1]:53"},
+		{"bracketed IPv6 with port", "[2001:db8::1]:53", "[2001:db8::1]:53"},
+		{"hostname", "resolver.example.com", "resolver.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addDefaultPort(tt.spec, "53"); got != tt.want {
+				t.Errorf("addDefaultPort(%q) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
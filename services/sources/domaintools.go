@@ -0,0 +1,133 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	eb "github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/resolvers"
+	"github.com/OWASP/Amass/v3/services"
+)
+
+// DomainTools is the Service that handles access to the DomainTools reverse whois data source.
+type DomainTools struct {
+	services.BaseService
+
+	API        *config.APIKey
+	SourceType string
+	RateLimit  time.Duration
+}
+
+// domainToolsResponse mirrors the subset of the DomainTools Reverse Whois
+// API response used here.
+type domainToolsResponse struct {
+	Response struct {
+		Domains []string `json:"domains"`
+	} `json:"response"`
+}
+
+// NewDomainTools returns he object initialized, but not yet started.
+func NewDomainTools(cfg *config.Config, bus *eb.EventBus, pool *resolvers.ResolverPool) *DomainTools {
+	d := &DomainTools{
+		SourceType: requests.API,
+		RateLimit:  10 * time.Second,
+	}
+
+	d.BaseService = *services.NewBaseService(d, "DomainTools", cfg, bus, pool)
+	return d
+}
+
+// OnStart implements the Service interface
+func (d *DomainTools) OnStart() error {
+	d.BaseService.OnStart()
+	d.API = d.Config().GetAPIKey(d.String())
+	if d.API == nil || d.API.Key == "" {
+		d.Bus().Publish(requests.LogTopic,
+			fmt.Sprintf("%s: API key data was not provided", d.String()),
+		)
+	}
+	d.Bus().Subscribe(requests.WhoisRequestTopic, d.SendWhoisRequest)
+
+	go d.processRequests()
+	return nil
+}
+
+func (d *DomainTools) processRequests() {
+	last := time.Now().Truncate(10 * time.Minute)
+
+	for {
+		select {
+		case <-d.Quit():
+			return
+
+		case whois := <-d.WhoisRequestChan():
+			if d.Config().IsDomainInScope(whois.Domain) {
+				if time.Now().Sub(last) < d.RateLimit {
+					time.Sleep(d.RateLimit)
+				}
+				last = time.Now()
+				d.executeWhoisQuery(whois)
+				last = time.Now()
+			}
+		case <-d.AddrRequestChan():
+		case <-d.ASNRequestChan():
+		}
+	}
+}
+
+// executeWhoisQuery runs the pivot described by whois and publishes anything
+// new to requests.NewWhoisTopic, deduplicated against every other
+// ReverseWhoisProvider.
+func (d *DomainTools) executeWhoisQuery(whois *requests.WhoisRequest) {
+	query := &AdvancedWhoisQuery{Domain: whois.Domain, Field: AdvancedWhoisField(whois.Field), Term: whois.Term}
+
+	found, err := d.ReverseWhois(query)
+	if err != nil {
+		d.Bus().Publish(requests.LogTopic, fmt.Sprintf("%s: %v", d.String(), err))
+		return
+	}
+
+	publishReverseWhois(d.Bus(), d.String(), d.SourceType, whois.Domain, found)
+}
+
+// ReverseWhois implements the ReverseWhoisProvider interface, pivoting on
+// query.Term for an advanced (email/org/ns/mx) search, or on query.Domain's
+// registrant details when no Field is set.
+func (d *DomainTools) ReverseWhois(query *AdvancedWhoisQuery) ([]string, error) {
+	if d.API == nil || d.API.Key == "" {
+		return nil, fmt.Errorf("API key data was not provided")
+	}
+
+	u := d.getReverseWhoisURL(query)
+
+	page, err := http.RequestWebPage(u, nil, nil, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", u, err)
+	}
+
+	var r domainToolsResponse
+	if err := json.NewDecoder(strings.NewReader(page)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode the DomainTools response: %v", err)
+	}
+	return r.Response.Domains, nil
+}
+
+// getReverseWhoisURL builds the DomainTools Reverse Whois API endpoint for
+// query, searching on the registrant term when query.Field is set and
+// falling back to query.Domain otherwise.
+func (d *DomainTools) getReverseWhoisURL(query *AdvancedWhoisQuery) string {
+	term := query.Domain
+	if query.Field != "" {
+		term = query.Term
+	}
+
+	return fmt.Sprintf("https://api.domaintools.com/v1/reverse-whois/?terms=%s&api_key=%s", term, d.API.Key)
+}
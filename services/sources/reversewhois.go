@@ -0,0 +1,115 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package sources
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	eb "github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// ReverseWhoisProvider is implemented by every source capable of pivoting a
+// domain's registration details into the other domains that share them. Each
+// provider is free to query whatever current/historic, basic/advanced
+// endpoint its API offers; ReverseWhois only needs to return the domains found.
+type ReverseWhoisProvider interface {
+	// ReverseWhois executes a single pivot, returning the domains discovered
+	// to share the registration detail described by query.
+	ReverseWhois(query *AdvancedWhoisQuery) ([]string, error)
+}
+
+// AdvancedWhoisField identifies the registrant detail a -whois-field pivot
+// searches on, in addition to (or instead of) a plain domain lookup.
+type AdvancedWhoisField string
+
+// Fields accepted by -whois-field, matching the advanced search terms the
+// WhoisXML, DomainTools, SecurityTrails, and WhoisFreaks APIs share. ViewDNS
+// ignores the field and pivots on the domain alone.
+const (
+	WhoisFieldEmail AdvancedWhoisField = "email"
+	WhoisFieldOrg   AdvancedWhoisField = "org"
+	WhoisFieldNS    AdvancedWhoisField = "ns"
+	WhoisFieldMX    AdvancedWhoisField = "mx"
+)
+
+// AdvancedWhoisQuery is the pivot a ReverseWhoisProvider is asked to perform:
+// a domain for a plain basic-search lookup, or a Field/Term pair for an
+// advanced search on registrant email, organization, name server, or MX.
+type AdvancedWhoisQuery struct {
+	Domain string
+	Field  AdvancedWhoisField
+	Term   string
+}
+
+// ParseWhoisField splits a "-whois-field" flag value of the form
+// "field:term" (e.g. "email:foo@bar.com") into its field/term pair.
+func ParseWhoisField(flag string) (AdvancedWhoisField, string, error) {
+	parts := strings.SplitN(flag, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("-whois-field must be field:term, e.g. email:foo@bar.com")
+	}
+
+	field := AdvancedWhoisField(strings.ToLower(parts[0]))
+	switch field {
+	case WhoisFieldEmail, WhoisFieldOrg, WhoisFieldNS, WhoisFieldMX:
+	default:
+		return "", "", fmt.Errorf("-whois-field: unsupported field %q", parts[0])
+	}
+
+	return field, parts[1], nil
+}
+
+// reverseWhoisDedup remembers, per originating domain, the associated
+// domains already published to requests.NewWhoisTopic, so the same pivot
+// result surfacing from more than one provider is only published once.
+type reverseWhoisDedup struct {
+	mu   sync.Mutex
+	seen map[string]map[string]struct{}
+}
+
+var reverseWhoisSeen = &reverseWhoisDedup{seen: make(map[string]map[string]struct{})}
+
+// filterNew returns the subset of found not yet published for domain,
+// recording it as seen so a later provider's overlapping results are dropped.
+func (d *reverseWhoisDedup) filterNew(domain string, found []string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	set, ok := d.seen[domain]
+	if !ok {
+		set = make(map[string]struct{})
+		d.seen[domain] = set
+	}
+
+	var fresh []string
+	for _, f := range found {
+		key := strings.ToLower(f)
+		if _, dup := set[key]; dup {
+			continue
+		}
+		set[key] = struct{}{}
+		fresh = append(fresh, f)
+	}
+	return fresh
+}
+
+// publishReverseWhois deduplicates found against every other provider's
+// results for domain and, when anything new survives, publishes a single
+// requests.WhoisRequest to requests.NewWhoisTopic on behalf of source/tag.
+func publishReverseWhois(bus *eb.EventBus, source, tag, domain string, found []string) {
+	fresh := reverseWhoisSeen.filterNew(domain, found)
+	if len(fresh) == 0 {
+		return
+	}
+
+	bus.Publish(requests.NewWhoisTopic, &requests.WhoisRequest{
+		Domain:     domain,
+		NewDomains: fresh,
+		Tag:        tag,
+		Source:     source,
+	})
+}
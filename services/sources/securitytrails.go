@@ -0,0 +1,132 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	eb "github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/resolvers"
+	"github.com/OWASP/Amass/v3/services"
+)
+
+// SecurityTrails is the Service that handles access to the SecurityTrails reverse whois data source.
+type SecurityTrails struct {
+	services.BaseService
+
+	API        *config.APIKey
+	SourceType string
+	RateLimit  time.Duration
+}
+
+// securityTrailsResponse mirrors the subset of the SecurityTrails WHOIS
+// search response used here.
+type securityTrailsResponse struct {
+	Result struct {
+		Domains []string `json:"domains"`
+	} `json:"result"`
+}
+
+// NewSecurityTrails returns he object initialized, but not yet started.
+func NewSecurityTrails(cfg *config.Config, bus *eb.EventBus, pool *resolvers.ResolverPool) *SecurityTrails {
+	s := &SecurityTrails{
+		SourceType: requests.API,
+		RateLimit:  10 * time.Second,
+	}
+
+	s.BaseService = *services.NewBaseService(s, "SecurityTrails", cfg, bus, pool)
+	return s
+}
+
+// OnStart implements the Service interface
+func (s *SecurityTrails) OnStart() error {
+	s.BaseService.OnStart()
+	s.API = s.Config().GetAPIKey(s.String())
+	if s.API == nil || s.API.Key == "" {
+		s.Bus().Publish(requests.LogTopic,
+			fmt.Sprintf("%s: API key data was not provided", s.String()),
+		)
+	}
+	s.Bus().Subscribe(requests.WhoisRequestTopic, s.SendWhoisRequest)
+
+	go s.processRequests()
+	return nil
+}
+
+func (s *SecurityTrails) processRequests() {
+	last := time.Now().Truncate(10 * time.Minute)
+
+	for {
+		select {
+		case <-s.Quit():
+			return
+
+		case whois := <-s.WhoisRequestChan():
+			if s.Config().IsDomainInScope(whois.Domain) {
+				if time.Now().Sub(last) < s.RateLimit {
+					time.Sleep(s.RateLimit)
+				}
+				last = time.Now()
+				s.executeWhoisQuery(whois)
+				last = time.Now()
+			}
+		case <-s.AddrRequestChan():
+		case <-s.ASNRequestChan():
+		}
+	}
+}
+
+// executeWhoisQuery runs the pivot described by whois and publishes anything
+// new to requests.NewWhoisTopic, deduplicated against every other
+// ReverseWhoisProvider.
+func (s *SecurityTrails) executeWhoisQuery(whois *requests.WhoisRequest) {
+	query := &AdvancedWhoisQuery{Domain: whois.Domain, Field: AdvancedWhoisField(whois.Field), Term: whois.Term}
+
+	found, err := s.ReverseWhois(query)
+	if err != nil {
+		s.Bus().Publish(requests.LogTopic, fmt.Sprintf("%s: %v", s.String(), err))
+		return
+	}
+
+	publishReverseWhois(s.Bus(), s.String(), s.SourceType, whois.Domain, found)
+}
+
+// ReverseWhois implements the ReverseWhoisProvider interface, pivoting on
+// query.Field/Term (SecurityTrails' whois/email, whois/ns, and whois/mx
+// search filters) when set, or on query.Domain's own registrant otherwise.
+func (s *SecurityTrails) ReverseWhois(query *AdvancedWhoisQuery) ([]string, error) {
+	if s.API == nil || s.API.Key == "" {
+		return nil, fmt.Errorf("API key data was not provided")
+	}
+
+	u := s.getReverseWhoisURL(query)
+	headers := map[string]string{"APIKEY": s.API.Key}
+
+	page, err := http.RequestWebPage(u, nil, headers, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", u, err)
+	}
+
+	var r securityTrailsResponse
+	if err := json.NewDecoder(strings.NewReader(page)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode the SecurityTrails response: %v", err)
+	}
+	return r.Result.Domains, nil
+}
+
+// getReverseWhoisURL builds the SecurityTrails reverse whois search endpoint
+// for query, searching on the registrant field/term when query.Field is set
+// and falling back to query.Domain otherwise.
+func (s *SecurityTrails) getReverseWhoisURL(query *AdvancedWhoisQuery) string {
+	if query.Field != "" {
+		return fmt.Sprintf("https://api.securitytrails.com/v1/search/whois?filter=%s:%s", query.Field, query.Term)
+	}
+	return fmt.Sprintf("https://api.securitytrails.com/v1/search/whois?filter=domain:%s", query.Domain)
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	eb "github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/resolvers"
+	"github.com/OWASP/Amass/v3/services"
+)
+
+// ViewDNS is the Service that handles access to the ViewDNS reverse whois data source.
+type ViewDNS struct {
+	services.BaseService
+
+	API        *config.APIKey
+	SourceType string
+	RateLimit  time.Duration
+}
+
+// viewDNSResponse mirrors the subset of the ViewDNS reverse whois response used here.
+type viewDNSResponse struct {
+	Query struct {
+		Tool string `json:"tool"`
+	} `json:"query"`
+	Response struct {
+		Domains []struct {
+			Name string `json:"domainName"`
+		} `json:"domains"`
+	} `json:"response"`
+}
+
+// NewViewDNS returns he object initialized, but not yet started.
+func NewViewDNS(cfg *config.Config, bus *eb.EventBus, pool *resolvers.ResolverPool) *ViewDNS {
+	v := &ViewDNS{
+		SourceType: requests.API,
+		RateLimit:  10 * time.Second,
+	}
+
+	v.BaseService = *services.NewBaseService(v, "ViewDNS", cfg, bus, pool)
+	return v
+}
+
+// OnStart implements the Service interface
+func (v *ViewDNS) OnStart() error {
+	v.BaseService.OnStart()
+	v.API = v.Config().GetAPIKey(v.String())
+	if v.API == nil || v.API.Key == "" {
+		v.Bus().Publish(requests.LogTopic,
+			fmt.Sprintf("%s: API key data was not provided", v.String()),
+		)
+	}
+	v.Bus().Subscribe(requests.WhoisRequestTopic, v.SendWhoisRequest)
+
+	go v.processRequests()
+	return nil
+}
+
+func (v *ViewDNS) processRequests() {
+	last := time.Now().Truncate(10 * time.Minute)
+
+	for {
+		select {
+		case <-v.Quit():
+			return
+
+		case whois := <-v.WhoisRequestChan():
+			if v.Config().IsDomainInScope(whois.Domain) {
+				if time.Now().Sub(last) < v.RateLimit {
+					time.Sleep(v.RateLimit)
+				}
+				last = time.Now()
+				v.executeWhoisQuery(whois)
+				last = time.Now()
+			}
+		case <-v.AddrRequestChan():
+		case <-v.ASNRequestChan():
+		}
+	}
+}
+
+// executeWhoisQuery runs the pivot described by whois and publishes anything
+// new to requests.NewWhoisTopic, deduplicated against every other
+// ReverseWhoisProvider.
+func (v *ViewDNS) executeWhoisQuery(whois *requests.WhoisRequest) {
+	query := &AdvancedWhoisQuery{Domain: whois.Domain, Field: AdvancedWhoisField(whois.Field), Term: whois.Term}
+
+	found, err := v.ReverseWhois(query)
+	if err != nil {
+		v.Bus().Publish(requests.LogTopic, fmt.Sprintf("%s: %v", v.String(), err))
+		return
+	}
+
+	publishReverseWhois(v.Bus(), v.String(), v.SourceType, whois.Domain, found)
+}
+
+// ReverseWhois implements the ReverseWhoisProvider interface. ViewDNS only
+// offers a basic (domain) reverse whois pivot, so an advanced query's Field
+// is ignored and the lookup falls back to query.Domain.
+func (v *ViewDNS) ReverseWhois(query *AdvancedWhoisQuery) ([]string, error) {
+	if v.API == nil || v.API.Key == "" {
+		return nil, fmt.Errorf("API key data was not provided")
+	}
+
+	u := v.getReverseWhoisURL(query.Domain)
+
+	page, err := http.RequestWebPage(u, nil, nil, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", u, err)
+	}
+
+	var r viewDNSResponse
+	if err := json.NewDecoder(strings.NewReader(page)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode the ViewDNS response: %v", err)
+	}
+
+	var found []string
+	for _, d := range r.Response.Domains {
+		found = append(found, d.Name)
+	}
+	return found, nil
+}
+
+// getReverseWhoisURL builds the ViewDNS reverse whois endpoint for domain.
+func (v *ViewDNS) getReverseWhoisURL(domain string) string {
+	return fmt.Sprintf("https://api.viewdns.info/reversewhois/?q=%s&apikey=%s&output=json", domain, v.API.Key)
+}
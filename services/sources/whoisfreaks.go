@@ -0,0 +1,136 @@
+// Copyright 2017 Jeff Foley. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	eb "github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/resolvers"
+	"github.com/OWASP/Amass/v3/services"
+)
+
+// WhoisFreaks is the Service that handles access to the WhoisFreaks reverse whois data source.
+type WhoisFreaks struct {
+	services.BaseService
+
+	API        *config.APIKey
+	SourceType string
+	RateLimit  time.Duration
+}
+
+// whoisFreaksResponse mirrors the subset of the WhoisFreaks Reverse Whois
+// API response used here.
+type whoisFreaksResponse struct {
+	TotalResultCount int      `json:"total_result_count"`
+	Domains          []string `json:"domains_list"`
+}
+
+// NewWhoisFreaks returns he object initialized, but not yet started.
+func NewWhoisFreaks(cfg *config.Config, bus *eb.EventBus, pool *resolvers.ResolverPool) *WhoisFreaks {
+	w := &WhoisFreaks{
+		SourceType: requests.API,
+		RateLimit:  10 * time.Second,
+	}
+
+	w.BaseService = *services.NewBaseService(w, "WhoisFreaks", cfg, bus, pool)
+	return w
+}
+
+// OnStart implements the Service interface
+func (w *WhoisFreaks) OnStart() error {
+	w.BaseService.OnStart()
+	w.API = w.Config().GetAPIKey(w.String())
+	if w.API == nil || w.API.Key == "" {
+		w.Bus().Publish(requests.LogTopic,
+			fmt.Sprintf("%s: API key data was not provided", w.String()),
+		)
+	}
+	w.Bus().Subscribe(requests.WhoisRequestTopic, w.SendWhoisRequest)
+
+	go w.processRequests()
+	return nil
+}
+
+func (w *WhoisFreaks) processRequests() {
+	last := time.Now().Truncate(10 * time.Minute)
+
+	for {
+		select {
+		case <-w.Quit():
+			return
+
+		case whois := <-w.WhoisRequestChan():
+			if w.Config().IsDomainInScope(whois.Domain) {
+				if time.Now().Sub(last) < w.RateLimit {
+					time.Sleep(w.RateLimit)
+				}
+				last = time.Now()
+				w.executeWhoisQuery(whois)
+				last = time.Now()
+			}
+		case <-w.AddrRequestChan():
+		case <-w.ASNRequestChan():
+		}
+	}
+}
+
+// executeWhoisQuery runs the pivot described by whois and publishes anything
+// new to requests.NewWhoisTopic, deduplicated against every other
+// ReverseWhoisProvider.
+func (w *WhoisFreaks) executeWhoisQuery(whois *requests.WhoisRequest) {
+	query := &AdvancedWhoisQuery{Domain: whois.Domain, Field: AdvancedWhoisField(whois.Field), Term: whois.Term}
+
+	found, err := w.ReverseWhois(query)
+	if err != nil {
+		w.Bus().Publish(requests.LogTopic, fmt.Sprintf("%s: %v", w.String(), err))
+		return
+	}
+
+	publishReverseWhois(w.Bus(), w.String(), w.SourceType, whois.Domain, found)
+}
+
+// ReverseWhois implements the ReverseWhoisProvider interface, pivoting on
+// query.Field/Term (WhoisFreaks' search_term/search_type parameters) when
+// set, or on query.Domain's own registrant details otherwise.
+func (w *WhoisFreaks) ReverseWhois(query *AdvancedWhoisQuery) ([]string, error) {
+	if w.API == nil || w.API.Key == "" {
+		return nil, fmt.Errorf("API key data was not provided")
+	}
+
+	u := w.getReverseWhoisURL(query)
+
+	page, err := http.RequestWebPage(u, nil, nil, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", u, err)
+	}
+
+	var r whoisFreaksResponse
+	if err := json.NewDecoder(strings.NewReader(page)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode the WhoisFreaks response: %v", err)
+	}
+	if r.TotalResultCount == 0 {
+		return nil, nil
+	}
+	return r.Domains, nil
+}
+
+// getReverseWhoisURL builds the WhoisFreaks Reverse Whois API endpoint for
+// query, searching on the registrant field/term when query.Field is set and
+// falling back to query.Domain otherwise.
+func (w *WhoisFreaks) getReverseWhoisURL(query *AdvancedWhoisQuery) string {
+	searchType, term := "domain", query.Domain
+	if query.Field != "" {
+		searchType, term = string(query.Field), query.Term
+	}
+
+	return fmt.Sprintf("https://api.whoisfreaks.com/v1.0/whois/reverse?apiKey=%s&search_type=%s&search_term=%s",
+		w.API.Key, searchType, term)
+}
@@ -10,12 +10,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/OWASP/Amass/config"
-	eb "github.com/OWASP/Amass/eventbus"
-	"github.com/OWASP/Amass/net/http"
-	"github.com/OWASP/Amass/requests"
-	"github.com/OWASP/Amass/resolvers"
-	"github.com/OWASP/Amass/services"
+	"github.com/OWASP/Amass/v3/config"
+	eb "github.com/OWASP/Amass/v3/eventbus"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/resolvers"
+	"github.com/OWASP/Amass/v3/services"
 )
 
 // WhoisXML is the Service that handles access to the WhoisXML data source.
@@ -25,6 +25,10 @@ type WhoisXML struct {
 	API        *config.APIKey
 	SourceType string
 	RateLimit  time.Duration
+
+	// SearchType selects between the WhoisXML "current" and "historic" data
+	// sets. It defaults to "historic" when left empty.
+	SearchType string
 }
 
 //WhoisXMLResponse handles WhoisXML response json
@@ -99,7 +103,7 @@ func (w *WhoisXML) processRequests() {
 					time.Sleep(w.RateLimit)
 				}
 				last = time.Now()
-				w.executeWhoisQuery(whois.Domain)
+				w.executeWhoisQuery(whois)
 				last = time.Now()
 			}
 		case <-w.AddrRequestChan():
@@ -108,50 +112,76 @@ func (w *WhoisXML) processRequests() {
 	}
 }
 
-func (w *WhoisXML) executeWhoisQuery(domain string) {
-	u := w.getReverseWhoisURL(domain)
-	if w.API == nil || w.API.Key == "" {
-		w.Bus().Publish(requests.LogTopic,
-			fmt.Sprintf("%s: API key data was not provided", w.String()),
-		)
+// executeWhoisQuery runs the pivot described by whois and, when it surfaces
+// results not already published by another ReverseWhoisProvider, publishes
+// them to requests.NewWhoisTopic.
+func (w *WhoisXML) executeWhoisQuery(whois *requests.WhoisRequest) {
+	query := &AdvancedWhoisQuery{
+		Domain: whois.Domain,
+		Field:  AdvancedWhoisField(whois.Field),
+		Term:   whois.Term,
+	}
+
+	found, err := w.ReverseWhois(query)
+	if err != nil {
+		w.Bus().Publish(requests.LogTopic, fmt.Sprintf("%s: %v", w.String(), err))
 		return
 	}
-	headers := map[string]string{"X-Authentication-Token": w.API.Key}
 
-	var r = WhoisXMLBasicRequest{
-		Search: "historic",
-		Mode:   "purchase",
+	publishReverseWhois(w.Bus(), w.String(), w.SourceType, whois.Domain, found)
+}
+
+// ReverseWhois implements the ReverseWhoisProvider interface, issuing either
+// a basic (domain) or advanced (email/org/ns/mx) search against the current
+// or historic WhoisXML data set, as selected by query and w.SearchType.
+func (w *WhoisXML) ReverseWhois(query *AdvancedWhoisQuery) ([]string, error) {
+	if w.API == nil || w.API.Key == "" {
+		return nil, fmt.Errorf("API key data was not provided")
 	}
-	r.SearchTerms.Include = append(r.SearchTerms.Include, domain)
-	jr, _ := json.Marshal(r)
 
-	page, err := http.RequestWebPage(u, bytes.NewReader(jr), headers, "", "")
+	searchType := w.SearchType
+	if searchType == "" {
+		searchType = "historic"
+	}
+
+	var jr []byte
+	var err error
+	if query.Field != "" {
+		jr, err = json.Marshal(WhoisXMLAdvanceRequest{
+			Search: searchType,
+			Mode:   "purchase",
+			SearchTerms: []WhoisXMLAdvanceSearchTerms{
+				{Field: string(query.Field), Term: query.Term},
+			},
+		})
+	} else {
+		r := WhoisXMLBasicRequest{Search: searchType, Mode: "purchase"}
+		r.SearchTerms.Include = append(r.SearchTerms.Include, query.Domain)
+		jr, err = json.Marshal(r)
+	}
 	if err != nil {
-		w.Bus().Publish(requests.LogTopic, fmt.Sprintf("%s: %s: %w", w.String(), u, err))
-		return
+		return nil, err
 	}
 
-	// Pull the table we need from the page content
-	var q WhoisXMLResponse
+	u := w.getReverseWhoisURL(query.Domain)
+	headers := map[string]string{"X-Authentication-Token": w.API.Key}
 
-	err = json.NewDecoder(strings.NewReader(page)).Decode(&q)
+	page, err := http.RequestWebPage(u, bytes.NewReader(jr), headers, "", "")
 	if err != nil {
-		w.Bus().Publish(requests.LogTopic,
-			fmt.Sprintf("Failed to decode json in WhoisXML.\nErr:%s", err))
-		return
+		return nil, fmt.Errorf("%s: %v", u, err)
 	}
-	if q.Found > 0 {
 
-		w.Bus().Publish(requests.NewWhoisTopic, &requests.WhoisRequest{
-			Domain:     domain,
-			NewDomains: q.List,
-			Tag:        w.SourceType,
-			Source:     w.String(),
-		})
+	var q WhoisXMLResponse
+	if err := json.NewDecoder(strings.NewReader(page)).Decode(&q); err != nil {
+		return nil, fmt.Errorf("failed to decode the WhoisXML response: %v", err)
+	}
+	if q.Found == 0 {
+		return nil, nil
 	}
+	return q.List, nil
 }
 
+// getReverseWhoisURL builds the WhoisXML reverse whois endpoint for domain.
 func (w *WhoisXML) getReverseWhoisURL(domain string) string {
-	format := "https://reverse-whois-api.whoisxmlapi.com/api/v2"
-	return fmt.Sprintf(format)
+	return fmt.Sprintf("https://reverse-whois-api.whoisxmlapi.com/api/v2?domain=%s", domain)
 }